@@ -0,0 +1,123 @@
+/*
+Copyright © 2020 Chung Tran <chung.k.tran@gmail.com>
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package cmd
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+// bookmarkCmd represents the bookmark command that switches to context and
+// namespace of a bookmark from `bookmarks` key in one step. A list of
+// bookmarks is presented for user to pick from when no argument is passed.
+var bookmarkCmd = &cobra.Command{
+	Use:     "bookmark [LABEL]",
+	Short:   "List or set bookmarked context and namespace",
+	Aliases: []string{"bm"},
+	Args:    cobra.MaximumNArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		ks := newKubeswitch()
+
+		bookmarks := viper.GetStringMapString("bookmarks")
+		var labels []string
+		for label := range bookmarks {
+			labels = append(labels, label)
+		}
+		sort.Strings(labels)
+
+		var label string
+		if len(args) > 0 {
+			// Labels are lowercase since config keys are case-insensitive.
+			label = strings.ToLower(args[0])
+		} else if noPrompt() {
+			list(&labels)
+			return
+		} else {
+			var err error
+			if label, err = selectOption("bookmark", labels); err != nil {
+				fail(err)
+			}
+		}
+
+		bookmark, ok := bookmarks[label]
+		if !ok {
+			failCode(fmt.Errorf("invalid bookmark, %s", label), exitUsage)
+		}
+		ctx, ns, err := parseBookmark(bookmark)
+		if err != nil {
+			failCode(err, exitConfig)
+		}
+
+		if ks.IsValidContext(resolveAlias(ctx)) {
+			confirmContext(cmd, resolveAlias(ctx))
+		}
+		if err := ks.SetContextNamespace(resolveAlias(ctx), ns); err != nil {
+			fail(err)
+		}
+	},
+}
+
+// parseBookmark returns context and namespace of bookmark formatted as
+// `context/namespace`. Context names can have `/` but namespaces can't,
+// so bookmark is split at the last `/`.
+func parseBookmark(bookmark string) (string, string, error) {
+	i := strings.LastIndex(bookmark, "/")
+	if i <= 0 || i == len(bookmark)-1 {
+		return "", "", fmt.Errorf("invalid bookmark %s, must be context/namespace", bookmark)
+	}
+	return bookmark[:i], bookmark[i+1:], nil
+}
+
+// bookmarkAddCmd represents the bookmark add command that saves current
+// context and namespace as a bookmark in Kubeswitch config file.
+var bookmarkAddCmd = &cobra.Command{
+	Use:   "add LABEL",
+	Short: "Bookmark current context and namespace",
+	Args:  cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		ks := newKubeswitch()
+
+		// Context without default namespace uses the default namespace.
+		ns := ks.CurrentNamespace()
+		if ns == "" {
+			ns = "default"
+		}
+
+		if err := addBookmark(args[0], ks.CurrentContext()+"/"+ns); err != nil {
+			fail(err)
+		}
+	},
+}
+
+// addBookmark saves bookmark under label in `bookmarks` key of Kubeswitch
+// config file.
+func addBookmark(label, bookmark string) error {
+	return updateConfig("bookmarks", func(cfg *viper.Viper) interface{} {
+		bookmarks := cfg.GetStringMapString("bookmarks")
+		bookmarks[strings.ToLower(label)] = bookmark
+		return bookmarks
+	})
+}
+
+func init() {
+	rootCmd.AddCommand(bookmarkCmd)
+	bookmarkCmd.Flags().BoolP("yes", "y", false, "switch to contexts in confirm key without confirmation")
+	bookmarkCmd.AddCommand(bookmarkAddCmd)
+}
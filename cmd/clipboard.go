@@ -0,0 +1,66 @@
+/*
+Copyright © 2020 Chung Tran <chung.k.tran@gmail.com>
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package cmd
+
+import (
+	"errors"
+	"os/exec"
+	"strings"
+)
+
+var (
+	// errNoClipboard is returned when no clipboard tool is installed.
+	errNoClipboard = errors.New("no clipboard tool found, install pbcopy, wl-copy, xclip, or xsel")
+
+	// clipboardCmds are commands copying their stdin to the system clipboard
+	// in order of preference.
+	clipboardCmds = [][]string{
+		{"pbcopy"},
+		{"wl-copy"},
+		{"xclip", "-selection", "clipboard"},
+		{"xsel", "--clipboard", "--input"},
+		{"clip.exe"},
+	}
+)
+
+// copyToClipboard copies text to the system clipboard using the first
+// clipboard tool installed.
+func copyToClipboard(text string) error {
+	for _, c := range clipboardCmds {
+		path, err := exec.LookPath(c[0])
+		if err != nil {
+			continue
+		}
+
+		verbose("Copying to clipboard with %s", c[0])
+		cmd := exec.Command(path, c[1:]...)
+		cmd.Stdin = strings.NewReader(text)
+		return cmd.Run()
+	}
+
+	return errNoClipboard
+}
+
+// copyOutput copies text to the system clipboard if `--copy-to-clipboard`
+// is set, only warning if it can't be copied since text is printed too.
+func copyOutput(clip bool, text string) {
+	if !clip {
+		return
+	}
+	if err := copyToClipboard(text); err != nil {
+		warn("not copied to clipboard, %s", err)
+	}
+}
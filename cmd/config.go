@@ -0,0 +1,162 @@
+/*
+Copyright © 2020 Chung Tran <chung.k.tran@gmail.com>
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/spf13/cobra"
+	"sigs.k8s.io/yaml"
+	"github.com/ckt114/kubeswitch/kubeswitch"
+)
+
+// configCmd represents the config command that groups commands
+// for inspecting and managing Kubernetes configs.
+var configCmd = &cobra.Command{
+	Use:   "config",
+	Short: "Inspect and manage Kubernetes configs",
+}
+
+// configFilesCmd represents the config files command that prints Kubernetes
+// config files merged into KUBECONFIG in order, marking missing files.
+var configFilesCmd = &cobra.Command{
+	Use:   "files",
+	Short: "List Kubernetes config files in use",
+	Args:  cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		// Session file is the only config file when in Kubeswitch session.
+		files := configFiles
+		if kubeswitch.IsActive() {
+			files = filepath.SplitList(os.Getenv(kubeswitch.EnvVarConfig))
+		}
+
+		for _, file := range files {
+			if _, err := os.Stat(file); err != nil {
+				fmt.Println(file, "(missing)")
+			} else {
+				fmt.Println(file)
+			}
+		}
+	},
+}
+
+// configMinifyCmd represents the config minify command that removes clusters
+// and users not referenced by any context from the loaded config.
+var configMinifyCmd = &cobra.Command{
+	Use:   "minify",
+	Short: "Remove unused clusters and users",
+	Args:  cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		ks := newKubeswitch()
+
+		// Minify session file in place when in Kubeswitch session.
+		output, _ := cmd.Flags().GetString("output")
+		if output == "" {
+			if !kubeswitch.IsActive() {
+				failCode("output is required outside of Kubeswitch session", exitUsage)
+			}
+			output = os.Getenv(kubeswitch.EnvVarConfig)
+		}
+
+		if err := ks.Minify(); err != nil {
+			fail(err)
+		}
+		if err := ks.WriteToFile(output); err != nil {
+			fail(err)
+		}
+	},
+}
+
+// configViewCmd represents the config view command that prints the loaded
+// config with credentials redacted.
+var configViewCmd = &cobra.Command{
+	Use:   "view",
+	Short: "Print Kubernetes config",
+	Args:  cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		ks := newKubeswitch()
+
+		minify, _ := cmd.Flags().GetBool("minify")
+		data, err := ks.View(minify)
+		if err != nil {
+			fail(err)
+		}
+
+		// Redact credentials unless raw config is requested.
+		if raw, _ := cmd.Flags().GetBool("raw"); !raw {
+			if data, err = redactConfig(data); err != nil {
+				fail(err)
+			}
+		}
+		fmt.Print(string(data))
+		clip, _ := cmd.Flags().GetBool("copy-to-clipboard")
+		copyOutput(clip, string(data))
+	},
+}
+
+// redactConfig returns YAML config data with values of sensitive keys redacted.
+func redactConfig(data []byte) ([]byte, error) {
+	var config map[string]interface{}
+	if err := yaml.Unmarshal(data, &config); err != nil {
+		return nil, err
+	}
+
+	return yaml.Marshal(redact(config))
+}
+
+// configEditCmd represents the config edit command that opens a Kubernetes
+// config file in the user's editor and validates it after editing.
+var configEditCmd = &cobra.Command{
+	Use:   "edit",
+	Short: "Edit Kubernetes config file",
+	Args:  cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		// Edit first config file in KUBECONFIG unless file is passed in.
+		file, _ := cmd.Flags().GetString("file")
+		if file == "" {
+			for _, f := range filepath.SplitList(os.Getenv(kubeswitch.EnvVarConfig)) {
+				if f != "" {
+					file = f
+					break
+				}
+			}
+		}
+		if file == "" {
+			failCode("no Kubernetes config file to edit, use --file", exitUsage)
+		}
+
+		if err := kubeswitch.Edit(file); err != nil {
+			failCode(err, exitConfig)
+		}
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(configCmd)
+	configCmd.AddCommand(configFilesCmd)
+	configCmd.AddCommand(configMinifyCmd)
+	configCmd.AddCommand(configEditCmd)
+	configCmd.AddCommand(configViewCmd)
+
+	// Local flags only available to this command.
+	configMinifyCmd.Flags().StringP("output", "o", "", "file to write minified config to (default session file)")
+	configViewCmd.Flags().Bool("minify", false, "only print current context and its cluster and user")
+	configViewCmd.Flags().Bool("copy-to-clipboard", false, "also copy printed config to clipboard")
+	configViewCmd.Flags().Bool("raw", false, "print credentials instead of redacting them")
+	configEditCmd.Flags().StringP("file", "f", "", "config file to edit (default first file in KUBECONFIG)")
+}
@@ -16,6 +16,16 @@ limitations under the License.
 package cmd
 
 import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+	"sort"
+	"strings"
+	"text/tabwriter"
+
 	"github.com/spf13/cobra"
 	"github.com/spf13/viper"
 	"github.com/ckt114/kubeswitch/kubeswitch"
@@ -32,41 +42,407 @@ var contextCmd = &cobra.Command{
 	Args:    cobra.MaximumNArgs(1),
 	Run: func(cmd *cobra.Command, args []string) {
 
+		// Read name from stdin instead of prompting user.
+		if stdin, _ := cmd.Flags().GetBool("stdin"); stdin {
+			var err error
+			if args, err = readArg(os.Stdin, args); err != nil {
+				failCode(err, exitUsage)
+			}
+		}
+
 		// Create an instance of Kubeswitch with passed in config if set.
-		ks, err := kubeswitch.New()
-		if err != nil {
-			fail(err)
+		ks := newKubeswitch()
+
+		// Load contexts from file that may not be in loaded config files.
+		if from, _ := cmd.Flags().GetString("from"); from != "" {
+			force, _ := cmd.Flags().GetBool("force")
+			if err := ks.MergeFile(from, force); err != nil {
+				failCode(err, exitConfig)
+			}
+		}
+
+		// Print table of contexts for an overview.
+		if table, _ := cmd.Flags().GetBool("table"); table {
+			filter, _ := cmd.Flags().GetString("filter")
+			noColor, _ := cmd.Flags().GetBool("no-color")
+			if err := printContextTable(os.Stdout, ks.ContextDetails(), filter, !noColor && isTerminal(os.Stdout)); err != nil {
+				fail(err)
+			}
+			return
 		}
 
 		// Prompt user to select a context since no context is passed in.
 		if len(args) < 1 {
-			// Get string list of contexts.
-			ctxs := *ks.ListContexts()
+			filter, _ := cmd.Flags().GetString("filter")
+
+			// Print number of contexts matching filter without aliases.
+			if count, _ := cmd.Flags().GetBool("count"); count {
+				names, err := filterItems(*ks.ListContexts(), filter)
+				if err != nil {
+					fail(err)
+				}
+				fmt.Println(len(names))
+				return
+			}
+
+			// Get string list of contexts matching filter.
+			ctxs, err := filterItems(withAliases(*ks.ListContexts()), filter)
+			if err != nil {
+				fail(err)
+			}
+			if ctxs, err = sortContexts(ctxs, ks.ContextDetails(), viper.GetString("contextSort")); err != nil {
+				failCode(err, exitUsage)
+			}
 
 			// List context one per line without prompt. Use for shell completion.
-			if viper.GetBool("noPrompt") {
+			if noPrompt() {
 				list(&ctxs)
 			} else {
-				// Prompt user to select context from a list.
-				c, err := selectOption("context", ctxs)
+				// Prompt user to select context from a list with favorites first.
+				c, err := selectOption("context", withFavorites(ctxs))
 				if err != nil {
 					fail(err)
 				}
+				c = strings.TrimPrefix(c, favoriteMarker)
+				confirmContext(cmd, resolveAlias(c))
 
 				// Set to selected context picked from prompt.
-				if err := ks.SetContext(c); err != nil {
+				if err := ks.SetContext(resolveAlias(c)); err != nil {
 					fail(err)
 				}
 			}
 		} else {
 			// Set to context provided as argument from command line.
-			if err := ks.SetContext(args[0]); err != nil {
+			c, err := resolveName("context", args[0], withAliases(*ks.ListContexts()))
+			if err != nil {
+				fail(err)
+			}
+			if ks.IsValidContext(resolveAlias(c)) {
+				confirmContext(cmd, resolveAlias(c))
+			}
+			if err := ks.SetContext(resolveAlias(c)); errors.Is(err, kubeswitch.ErrInvalidContext) {
+				fail(fmt.Errorf("%w, run `kubeswitch context` to list contexts", err))
+			} else if err != nil {
+				fail(err)
+			}
+		}
+	},
+}
+
+// confirmContext asks user to confirm switching to ctx if it matches a pattern
+// in `confirm` key. It exits unless confirmed or --yes is passed in.
+func confirmContext(cmd *cobra.Command, ctx string) {
+	if yes, _ := cmd.Flags().GetBool("yes"); yes {
+		return
+	}
+
+	confirm, err := needsConfirm(ctx)
+	if err != nil {
+		failCode(err, exitConfig)
+	}
+	if !confirm {
+		return
+	}
+	if !interactive() {
+		failCode(fmt.Sprintf("context %s requires confirmation, use --yes to switch without prompt", ctx), exitUsage)
+	}
+
+	if err := confirmPrompt(fmt.Sprintf("Switch to context %s", ctx)); err != nil {
+		fail(err)
+	}
+}
+
+// needsConfirm returns true if ctx matches any regexp pattern in `confirm` key.
+func needsConfirm(ctx string) (bool, error) {
+	for _, pattern := range viper.GetStringSlice("confirm") {
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			return false, fmt.Errorf("invalid confirm pattern %q: %v", pattern, err)
+		}
+		if re.MatchString(ctx) {
+			return true, nil
+		}
+	}
+
+	return false, nil
+}
+
+// printContextTable writes details of contexts in infos matching filter to w
+// as a table with the current context marked and colored if color is true.
+func printContextTable(w io.Writer, infos []kubeswitch.ContextInfo, filter string, color bool) error {
+	var names []string
+	for _, info := range infos {
+		names = append(names, info.Name)
+	}
+	names, err := filterItems(names, filter)
+	if err != nil {
+		return err
+	}
+	matched := map[string]bool{}
+	for _, n := range names {
+		matched[n] = true
+	}
+
+	var buf bytes.Buffer
+	tw := tabwriter.NewWriter(&buf, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "CURRENT\tNAME\tCLUSTER\tNAMESPACE")
+	for _, info := range infos {
+		if !matched[info.Name] {
+			continue
+		}
+		current := ""
+		if info.Current {
+			current = "*"
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\n", current, info.Name, info.Cluster, info.Namespace)
+	}
+	if err := tw.Flush(); err != nil {
+		return err
+	}
+
+	// Color rows after aligning columns since escape codes would be
+	// counted as part of the column width.
+	for _, line := range strings.SplitAfter(buf.String(), "\n") {
+		if color && strings.HasPrefix(line, "*") {
+			line = promptColor + strings.TrimSuffix(line, "\n") + promptReset + "\n"
+		}
+		if _, err := io.WriteString(w, line); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// resolveAlias returns the context name that name is an alias of in `aliases`
+// key. Name is returned as is if it's not an alias.
+func resolveAlias(name string) string {
+	// Alias names are lowercase since config keys are case-insensitive.
+	if ctx, ok := viper.GetStringMapString("aliases")[strings.ToLower(name)]; ok {
+		return ctx
+	}
+	return name
+}
+
+// withAliases returns ctxs with names of aliases of contexts in ctxs
+// from `aliases` key sorted.
+func withAliases(ctxs []string) []string {
+	exists := map[string]bool{}
+	for _, c := range ctxs {
+		exists[c] = true
+	}
+
+	result := append([]string{}, ctxs...)
+	for alias, ctx := range viper.GetStringMapString("aliases") {
+		if exists[ctx] && !exists[alias] {
+			result = append(result, alias)
+		}
+	}
+	sort.Strings(result)
+
+	return result
+}
+
+// sortContexts returns ctxs, which may include aliases, sorted by name, by
+// cluster, or by recent for last switched to first using details in infos.
+// Contexts with the same cluster or never switched to are sorted by name.
+func sortContexts(ctxs []string, infos []kubeswitch.ContextInfo, by string) ([]string, error) {
+	details := map[string]kubeswitch.ContextInfo{}
+	for _, info := range infos {
+		details[info.Name] = info
+	}
+
+	var less func(a, b kubeswitch.ContextInfo) bool
+	switch by {
+	case "name":
+		less = func(a, b kubeswitch.ContextInfo) bool { return false }
+	case "cluster":
+		less = func(a, b kubeswitch.ContextInfo) bool { return a.Cluster < b.Cluster }
+	case "recent":
+		less = func(a, b kubeswitch.ContextInfo) bool { return a.LastUsed.After(b.LastUsed) }
+	default:
+		return nil, fmt.Errorf("invalid sort order %s, must be one of: name, cluster, recent", by)
+	}
+
+	result := append([]string{}, ctxs...)
+	sort.Strings(result)
+	sort.SliceStable(result, func(i, j int) bool {
+		return less(details[resolveAlias(result[i])], details[resolveAlias(result[j])])
+	})
+
+	return result, nil
+}
+
+// withFavorites returns ctxs with contexts in `favorites` key first marked
+// with favoriteMarker followed by the rest of ctxs.
+func withFavorites(ctxs []string) []string {
+	favorites := map[string]bool{}
+	for _, f := range viper.GetStringSlice("favorites") {
+		favorites[f] = true
+	}
+
+	var pinned, rest []string
+	for _, c := range ctxs {
+		if favorites[c] {
+			pinned = append(pinned, favoriteMarker+c)
+		} else {
+			rest = append(rest, c)
+		}
+	}
+
+	return append(pinned, rest...)
+}
+
+// setFavorite adds context ctx to or removes it from `favorites` key
+// of Kubeswitch config file.
+func setFavorite(ctx string, pin bool) error {
+	return updateConfig("favorites", func(cfg *viper.Viper) interface{} {
+		var favorites []string
+		for _, f := range cfg.GetStringSlice("favorites") {
+			if f != ctx {
+				favorites = append(favorites, f)
+			}
+		}
+		if pin {
+			favorites = append(favorites, ctx)
+		}
+		return favorites
+	})
+}
+
+// contextPinCmd represents the context pin command that adds
+// a context to favorites listed first in the selection prompt.
+var contextPinCmd = &cobra.Command{
+	Use:   "pin NAME",
+	Short: "Pin context to top of selection prompt",
+	Args:  cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		ks := newKubeswitch()
+
+		c, err := resolveName("context", args[0], withAliases(*ks.ListContexts()))
+		if err != nil {
+			fail(err)
+		}
+		c = resolveAlias(c)
+		if !ks.IsValidContext(c) {
+			fail(fmt.Errorf("%w, %s", kubeswitch.ErrInvalidContext, c))
+		}
+
+		if err := setFavorite(c, true); err != nil {
+			fail(err)
+		}
+	},
+}
+
+// contextUnpinCmd represents the context unpin command that removes
+// a context from favorites.
+var contextUnpinCmd = &cobra.Command{
+	Use:   "unpin NAME",
+	Short: "Unpin context from top of selection prompt",
+	Args:  cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		if err := setFavorite(resolveAlias(args[0]), false); err != nil {
+			fail(err)
+		}
+	},
+}
+
+// contextWhereCmd represents the context where command that prints
+// the config files defining a context.
+var contextWhereCmd = &cobra.Command{
+	Use:   "where NAME",
+	Short: "Print config files defining context",
+	Args:  cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		paths, err := kubeswitch.Where(resolveAlias(args[0]))
+		if err != nil {
+			failCode(err, exitConfig)
+		}
+		if len(paths) == 0 {
+			fail(fmt.Errorf("%w, %s", kubeswitch.ErrInvalidContext, args[0]))
+		}
+
+		for _, path := range paths {
+			fmt.Println(path)
+		}
+	},
+}
+
+// contextFindCmd represents the context find command that prints contexts
+// whose cluster's server URL contains the passed in server.
+var contextFindCmd = &cobra.Command{
+	Use:   "find",
+	Short: "Find contexts by cluster server URL",
+	Args:  cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		server, _ := cmd.Flags().GetString("server")
+		if server == "" {
+			failCode("server is required", exitUsage)
+		}
+
+		ks := newKubeswitch()
+		ctxs := ks.FindContextsByServer(server)
+		if len(ctxs) == 0 {
+			fail(fmt.Errorf("no context with server matching %s", server))
+		}
+		list(&ctxs)
+	},
+}
+
+// contextExportCmd represents the context export command that writes
+// a context and its cluster and user to a standalone config file.
+var contextExportCmd = &cobra.Command{
+	Use:   "export [NAME]",
+	Short: "Export context to a file",
+	Args:  cobra.MaximumNArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		ks := newKubeswitch()
+
+		// Export current context if no context is passed in.
+		var c string
+		if len(args) > 0 {
+			var err error
+			if c, err = resolveName("context", args[0], withAliases(*ks.ListContexts())); err != nil {
 				fail(err)
 			}
+			c = resolveAlias(c)
+		}
+
+		output, _ := cmd.Flags().GetString("output")
+		noCreds, _ := cmd.Flags().GetBool("minify-no-creds")
+		exported, err := ks.Export(c, noCreds)
+		if err != nil {
+			fail(err)
+		}
+		if err := writeOutput(exported, output); err != nil {
+			fail(err)
 		}
 	},
 }
 
 func init() {
 	rootCmd.AddCommand(contextCmd)
+	contextCmd.AddCommand(contextExportCmd)
+	contextCmd.AddCommand(contextPinCmd)
+	contextCmd.AddCommand(contextUnpinCmd)
+	contextCmd.AddCommand(contextWhereCmd)
+	contextCmd.AddCommand(contextFindCmd)
+
+	// Local flags only available to this command.
+	contextCmd.Flags().StringP("filter", "f", "", "regexp to filter listed contexts")
+	contextCmd.Flags().Bool("stdin", false, "read context to set from stdin when not passed as argument")
+	contextCmd.Flags().String("from", "", "also load contexts from config file not in KUBECONFIG or configs")
+	contextCmd.Flags().Bool("force", false, "replace loaded contexts, clusters, and users with the ones from --from file")
+	contextCmd.Flags().BoolP("yes", "y", false, "switch to contexts in confirm key without confirmation")
+	contextCmd.Flags().String("sort", "name", "sort contexts by name, cluster, or recent for last switched to first (KUBESWITCH_CONTEXTSORT)")
+	viper.BindPFlag("contextSort", contextCmd.Flags().Lookup("sort"))
+	contextCmd.Flags().Bool("count", false, "print number of contexts matching filter")
+	contextCmd.Flags().Bool("table", false, "print table of contexts with their cluster and namespace")
+	contextCmd.Flags().Bool("no-color", false, "don't color current context in table")
+	contextCmd.Flags().Bool("no-restore", false, "don't restore last selected namespace (KUBESWITCH_NORESTORE)")
+	viper.BindPFlag("noRestore", contextCmd.Flags().Lookup("no-restore"))
+
+	contextFindCmd.Flags().String("server", "", "full or partial server URL of cluster")
+	contextExportCmd.Flags().StringP("output", "o", "", "file to write exported config to (default stdout)")
+	contextExportCmd.Flags().Bool("minify-no-creds", false, "strip user credentials from exported config")
 }
@@ -0,0 +1,66 @@
+/*
+Copyright © 2020 Chung Tran <chung.k.tran@gmail.com>
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package cmd
+
+import (
+	"os"
+	"path/filepath"
+
+	homedir "github.com/mitchellh/go-homedir"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+	"github.com/ckt114/kubeswitch/kubeswitch"
+)
+
+// importCmd represents the import command that copies a Kubernetes config
+// file into the folder managed by kubeswitch.
+var importCmd = &cobra.Command{
+	Use:   "import FILE",
+	Short: "Import Kubernetes config",
+	Args:  cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		name, _ := cmd.Flags().GetString("name")
+
+		dest, err := kubeswitch.Import(args[0], name)
+		if err != nil {
+			fail(err)
+		}
+		info("imported %s", dest)
+
+		// Suggest adding import folder to `configs` key so the config is used.
+		if !isConfigured(dest) {
+			info("add \"%s\" to configs key to use imported configs", filepath.Join(filepath.Dir(dest), "*"))
+		}
+	},
+}
+
+// isConfigured returns true if path matches a pattern in `configs` key.
+func isConfigured(path string) bool {
+	for _, pattern := range viper.GetStringSlice("configs") {
+		absPath, _ := homedir.Expand(os.ExpandEnv(pattern))
+		if ok, _ := filepath.Match(absPath, path); ok {
+			return true
+		}
+	}
+	return false
+}
+
+func init() {
+	rootCmd.AddCommand(importCmd)
+
+	// Local flags only available to this command.
+	importCmd.Flags().String("name", "", "rename the config's only context to name")
+}
@@ -0,0 +1,49 @@
+/*
+Copyright © 2020 Chung Tran <chung.k.tran@gmail.com>
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package cmd
+
+import (
+	"github.com/spf13/cobra"
+	"github.com/ckt114/kubeswitch/kubeswitch"
+)
+
+// mergeCmd represents the merge command that flattens multiple
+// Kubernetes config files into a single file.
+var mergeCmd = &cobra.Command{
+	Use:   "merge FILE...",
+	Short: "Merge Kubernetes configs into a single file",
+	Args:  cobra.MinimumNArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		output, _ := cmd.Flags().GetString("output")
+		prefix, _ := cmd.Flags().GetBool("prefix")
+
+		ks, err := kubeswitch.Merge(args, prefix)
+		if err != nil {
+			fail(err)
+		}
+		if err := writeOutput(ks, output); err != nil {
+			fail(err)
+		}
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(mergeCmd)
+
+	// Local flags only available to this command.
+	mergeCmd.Flags().StringP("output", "o", "", "file to write merged config to (default stdout)")
+	mergeCmd.Flags().Bool("prefix", false, "prefix names with their file name to avoid collisions")
+}
@@ -16,8 +16,21 @@ limitations under the License.
 package cmd
 
 import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/manifoldco/promptui"
 	"github.com/spf13/cobra"
 	"github.com/spf13/viper"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	"github.com/ckt114/kubeswitch/kubeswitch"
 )
 
@@ -32,24 +45,94 @@ var namespaceCmd = &cobra.Command{
 	Args:    cobra.MaximumNArgs(1),
 	Run: func(cmd *cobra.Command, args []string) {
 
-		// Create an instance of Kubeswitch with config from default location.
-		ks, err := kubeswitch.New()
-		if err != nil {
-			fail(err)
+		// Read name from stdin instead of prompting user.
+		if stdin, _ := cmd.Flags().GetBool("stdin"); stdin {
+			var err error
+			if args, err = readArg(os.Stdin, args); err != nil {
+				failCode(err, exitUsage)
+			}
 		}
 
+		// Create an instance of Kubeswitch with config from default location.
+		ks := newKubeswitch()
+
 		// Load namespaces for current context live from Kubernetes.
-		if err := ks.LoadNamespaces(); err != nil {
-			fail(err)
+		setupClient(cmd, ks)
+		switch sortBy := viper.GetString("sort"); sortBy {
+		case "recent":
+			ks.SortRecent = true
+		case "name":
+		default:
+			failCode(fmt.Errorf("invalid sort order, %s", sortBy), exitUsage)
+		}
+
+		// Select namespace of several contexts.
+		if across, _ := cmd.Flags().GetStringSlice("across"); len(across) > 0 {
+			setNamespaceAcross(cmd, ks, across)
+			return
+		}
+
+		ctx, cancel := apiContext()
+		defer cancel()
+
+		// Check cluster is reachable to fail fast with a clear error.
+		if !viper.GetBool("noHealthCheck") {
+			if err := ks.CheckHealthContext(ctx); err != nil {
+				fail(apiError(ctx, err))
+			}
+		}
+
+		// Print namespaces again whenever they change until user interrupts
+		// or no change is seen for timeout.
+		if watch, _ := cmd.Flags().GetBool("watch"); watch {
+			filter, _ := cmd.Flags().GetString("filter")
+			if err := watchNamespaces(cmd, ks, filter); err != nil {
+				fail(err)
+			}
+			return
+		}
+
+		if err := ks.LoadNamespacesContext(ctx); apierrors.IsForbidden(err) {
+			// Let user enter namespace since namespaces can't be listed.
+			warn("%s; namespace is not verified", err)
+			setUnlistedNamespace(ks, args)
+			return
+		} else if err != nil {
+			fail(apiError(ctx, err))
 		}
 
 		// Prompt user to select a namespace since no namespace is passed in.
 		if len(args) < 1 {
-			// Get a string list of namespaces.
-			nss := *ks.ListNamespaces()
+			// Get a string list of namespaces matching filter.
+			filter, _ := cmd.Flags().GetString("filter")
+			nss, err := filterItems(*ks.ListNamespaces(), filter)
+			if err != nil {
+				fail(err)
+			}
+
+			// Print number of namespaces matching filter.
+			if count, _ := cmd.Flags().GetBool("count"); count {
+				fmt.Println(len(nss))
+				return
+			}
+
+			// Print details of namespaces in requested format.
+			output, _ := cmd.Flags().GetString("output")
+			if output != "" {
+				if err := printNamespaces(ks.NamespaceDetails(), nss, output); err != nil {
+					fail(err)
+				}
+				return
+			}
+
+			// Write a session file for each selected namespace.
+			if multi, _ := cmd.Flags().GetBool("multi"); multi {
+				writeNamespaceSessions(ks, nss)
+				return
+			}
 
 			// List namespaces one per line without prompt. Use for shell completion.
-			if viper.GetBool("noPrompt") {
+			if noPrompt() {
 				list(&nss)
 			} else {
 				// Prompt user to select namespace from a list.
@@ -59,20 +142,379 @@ var namespaceCmd = &cobra.Command{
 				}
 
 				// Set to selected namespace picked from prompt.
+				waitNamespace(cmd, ks, n)
 				if err := ks.SetNamespace(n); err != nil {
 					fail(err)
 				}
 			}
 
 		} else {
-			// Set to namespace provided as argument from command line.
-			if err := ks.SetNamespace(args[0]); err != nil {
+			// Set to namespace provided as argument from command line,
+			// creating it if it doesn't exist.
+			create, _ := cmd.Flags().GetBool("create")
+			n, create, err := resolveNamespace(ks, args[0], create)
+			if err != nil {
+				fail(err)
+			}
+			if create {
+				createNamespace(ctx, cmd, ks, n)
+				return
+			}
+
+			waitNamespace(cmd, ks, n)
+			if err := ks.SetNamespace(n); errors.Is(err, kubeswitch.ErrInvalidNamespace) {
+				fail(fmt.Errorf("%w, run `kubeswitch namespace` to list namespaces", err))
+			} else if err != nil {
+				fail(err)
+			}
+		}
+	},
+}
+
+// watchNamespaces lets user select a namespace matching filter from a prompt
+// that's redrawn whenever namespaces change, and sets it. Without prompt,
+// namespaces are printed whenever they change instead. Watching stops when
+// user interrupts or no change is seen for timeout.
+func watchNamespaces(cmd *cobra.Command, ks *kubeswitch.Kubeswitch, filter string) error {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	if noPrompt() {
+		err := ks.WatchNamespacesContext(ctx, viper.GetDuration("timeout"), func(nss []string) {
+			nss, err := filterItems(nss, filter)
+			if err != nil {
+				fail(err)
+			}
+			if isTerminal(os.Stdout) {
+				fmt.Print(clearScreen)
+			}
+			list(&nss)
+		})
+
+		// Interrupting the watch is how user stops it.
+		if errors.Is(err, context.Canceled) {
+			return nil
+		}
+		return err
+	}
+
+	// Watch in background and pass changed namespaces to the prompt.
+	ctx, cancel := context.WithCancel(ctx)
+	updates := make(chan []string, 1)
+	errc := make(chan error, 1)
+	go func() {
+		errc <- ks.WatchNamespacesContext(ctx, viper.GetDuration("timeout"), func(nss []string) {
+			nss, err := filterItems(nss, filter)
+			if err != nil {
 				fail(err)
 			}
+
+			// Only the latest namespaces are shown.
+			select {
+			case <-updates:
+			default:
+			}
+			updates <- nss
+		})
+	}()
+
+	n, err := liveSelect(updates, errc, newPromptInput(os.Stdin), func(nss []string, stdin io.ReadCloser) (string, error) {
+		prompt, err := newSelect("namespace", nss)
+		if err != nil {
+			return "", err
+		}
+		prompt.Stdin = stdin
+		_, n, err := prompt.Run()
+		return n, err
+	})
+
+	// Stop watching before using namespaces updated by the watch.
+	cancel()
+	if n == "" {
+		if errors.Is(err, context.Canceled) {
+			return nil
+		}
+		return err
+	}
+	<-errc
+
+	waitNamespace(cmd, ks, n)
+	return ks.SetNamespace(n)
+}
+
+// writeNamespaceSessions prompts user to select several namespaces of nss and
+// prints paths of session files written for each of them.
+func writeNamespaceSessions(ks *kubeswitch.Kubeswitch, nss []string) {
+	if noPrompt() {
+		failCode("multi requires selection prompt", exitUsage)
+	}
+
+	selected, err := selectOptions("namespace", nss)
+	if err != nil {
+		fail(err)
+	}
+
+	paths, err := ks.WriteNamespaceSessions(selected)
+	if err != nil {
+		fail(err)
+	}
+	list(&paths)
+}
+
+// setupClient sets options of ks used when talking to Kubernetes from flags
+// and config. Use it in every namespace command that calls Kubernetes.
+func setupClient(cmd *cobra.Command, ks *kubeswitch.Kubeswitch) {
+	ks.PageSize = viper.GetInt64("pageSize")
+	ks.RetryAttempts = viper.GetInt("retry.attempts")
+	ks.RetryDelay = viper.GetDuration("retry.delay")
+	ks.Concurrency = viper.GetInt("concurrency")
+	ks.As, _ = cmd.Flags().GetString("as")
+	ks.AsGroups, _ = cmd.Flags().GetStringSlice("as-group")
+	ks.Insecure = viper.GetBool("insecure")
+}
+
+// confirmCreate asks user whether to create namespace ns that doesn't exist.
+var confirmCreate = func(ns string) bool {
+	if err := confirmPrompt(fmt.Sprintf("Namespace %s doesn't exist. Create it", ns)); err == promptui.ErrAbort {
+		return false
+	} else if err != nil {
+		fail(err)
+	}
+
+	return true
+}
+
+// resolveNamespace returns the namespace to set for name and whether to
+// create it. Name is created as is when it doesn't exist and create is set.
+// Otherwise it's resolved to an existing namespace, and user is asked to
+// create it when nothing matches.
+func resolveNamespace(ks *kubeswitch.Kubeswitch, name string, create bool) (string, bool, error) {
+	if create && !ks.IsValidNamespace(name) {
+		return name, true, nil
+	}
+
+	n, err := resolveName("namespace", name, *ks.ListNamespaces())
+	if err != nil {
+		return "", false, err
+	}
+
+	// Names with surrounding whitespace are trimmed when setting them.
+	exists := ks.IsValidNamespace(n) || ks.IsValidNamespace(strings.TrimSpace(n))
+	if !exists && !noPrompt() && confirmCreate(n) {
+		return n, true, nil
+	}
+
+	return n, false, nil
+}
+
+// createNamespace creates namespace ns and switches to it, waiting for it
+// to be active if `--wait` is set.
+func createNamespace(ctx context.Context, cmd *cobra.Command, ks *kubeswitch.Kubeswitch, ns string) {
+	ks.Wait, _ = cmd.Flags().GetBool("wait")
+	if err := ks.CreateNamespaceContext(ctx, ns); err != nil {
+		fail(apiError(ctx, err))
+	}
+}
+
+// waitNamespace waits for namespace ns to be active if `--wait` is set.
+func waitNamespace(cmd *cobra.Command, ks *kubeswitch.Kubeswitch, ns string) {
+	if wait, _ := cmd.Flags().GetBool("wait"); !wait || !ks.IsValidNamespace(ns) {
+		return
+	}
+
+	ctx, cancel := apiContext()
+	defer cancel()
+	if err := ks.WaitNamespaceContext(ctx, ns); err != nil {
+		fail(apiError(ctx, err))
+	}
+}
+
+// setUnlistedNamespace sets namespace passed in args or entered by user
+// without checking it exists in Kubernetes.
+func setUnlistedNamespace(ks *kubeswitch.Kubeswitch, args []string) {
+	var ns string
+	if len(args) > 0 {
+		ns = args[0]
+	} else if noPrompt() {
+		failCode("namespaces can't be listed, pass namespace as argument", exitUsage)
+	} else {
+		var err error
+		if ns, err = inputOption("namespace"); err != nil {
+			fail(err)
+		}
+	}
+
+	ks.AddNamespace(ns)
+	if err := ks.SetNamespace(ns); err != nil {
+		fail(err)
+	}
+}
+
+// setNamespaceAcross prompts user to select a namespace from namespaces of
+// contexts in ctxs and sets both context and namespace of the selection.
+func setNamespaceAcross(cmd *cobra.Command, ks *kubeswitch.Kubeswitch, ctxs []string) {
+	ctx, cancel := apiContext()
+	defer cancel()
+	nssByCtx, err := ks.LoadNamespacesForContextsContext(ctx, ctxs)
+	if err != nil {
+		// Only fail if namespaces of no contexts can be listed.
+		if len(nssByCtx) == 0 {
+			fail(apiError(ctx, err))
+		}
+		warn("%s", err)
+	}
+
+	// Prefix namespaces with their context.
+	var items []string
+	selections := map[string][2]string{}
+	for c, nss := range nssByCtx {
+		for _, n := range nss {
+			item := c + "/" + n
+			items = append(items, item)
+			selections[item] = [2]string{c, n}
+		}
+	}
+	sort.Strings(items)
+
+	// List namespaces one per line without prompt. Use for shell completion.
+	if noPrompt() {
+		list(&items)
+		return
+	}
+
+	item, err := selectOption("namespace", items)
+	if err != nil {
+		fail(err)
+	}
+	confirmContext(cmd, selections[item][0])
+	if err := ks.SetContextNamespace(selections[item][0], selections[item][1]); err != nil {
+		fail(err)
+	}
+}
+
+// printNamespaces prints details of namespaces named in names in output format.
+func printNamespaces(infos []kubeswitch.NamespaceInfo, names []string, output string) error {
+	if output != "json" {
+		return fmt.Errorf("invalid output format, %s", output)
+	}
+
+	// Only print namespaces matching names.
+	matched := map[string]bool{}
+	for _, n := range names {
+		matched[n] = true
+	}
+	result := []kubeswitch.NamespaceInfo{}
+	for _, info := range infos {
+		if matched[info.Name] {
+			result = append(result, info)
+		}
+	}
+
+	data, err := json.MarshalIndent(result, "", "  ")
+	if err != nil {
+		return err
+	}
+	fmt.Println(string(data))
+
+	return nil
+}
+
+// namespaceCreateCmd represents the namespace create command that creates
+// a namespace in Kubernetes and switches to it.
+var namespaceCreateCmd = &cobra.Command{
+	Use:   "create NAME",
+	Short: "Create and set namespace",
+	Args:  cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		ks := newKubeswitch()
+		setupClient(cmd, ks)
+
+		// Create namespace and set it as default namespace of current context.
+		ks.Wait, _ = cmd.Flags().GetBool("wait")
+		ctx, cancel := apiContext()
+		defer cancel()
+		if err := ks.CreateNamespaceContext(ctx, args[0]); err != nil {
+			fail(apiError(ctx, err))
+		}
+	},
+}
+
+// namespaceCheckCmd represents the namespace check command that warns if
+// namespace of current context no longer exists in Kubernetes.
+var namespaceCheckCmd = &cobra.Command{
+	Use:   "check",
+	Short: "Check namespace of current context exists",
+	Args:  cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		ks := newKubeswitch()
+		setupClient(cmd, ks)
+
+		ctx, cancel := apiContext()
+		defer cancel()
+		exists, err := ks.CurrentNamespaceExistsContext(ctx)
+		if err != nil {
+			fail(apiError(ctx, err))
+		}
+
+		// Exit with error so scripts can act on a stale namespace.
+		if !exists {
+			warn("namespace %s of context %s no longer exists, run `kubeswitch namespace` to switch to another namespace", ks.CurrentNamespace(), ks.CurrentContext())
+			os.Exit(exitError)
+		}
+	},
+}
+
+// namespaceUnsetCmd represents the namespace unset command that clears
+// the default namespace of current context.
+var namespaceUnsetCmd = &cobra.Command{
+	Use:   "unset",
+	Short: "Unset namespace",
+	Args:  cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		ks := newKubeswitch()
+
+		if err := ks.UnsetNamespace(); err != nil {
+			fail(err)
 		}
 	},
 }
 
 func init() {
 	rootCmd.AddCommand(namespaceCmd)
+	namespaceCmd.AddCommand(namespaceCreateCmd)
+	namespaceCmd.AddCommand(namespaceUnsetCmd)
+	namespaceCmd.AddCommand(namespaceCheckCmd)
+
+	// Local flags only available to this command.
+	namespaceCmd.Flags().StringP("filter", "f", "", "regexp to filter listed namespaces")
+	namespaceCmd.Flags().Bool("stdin", false, "read namespace to set from stdin when not passed as argument")
+	namespaceCmd.Flags().Bool("count", false, "print number of namespaces matching filter")
+	namespaceCmd.Flags().Bool("multi", false, "select several namespaces and write a session file for each")
+	namespaceCmd.Flags().Bool("create", false, "create namespace passed as argument if it doesn't exist")
+	namespaceCmd.Flags().StringP("output", "o", "", "print namespace details in format (json)")
+	namespaceCmd.Flags().Bool("no-health-check", false, "don't check cluster is reachable before listing namespaces (KUBESWITCH_NOHEALTHCHECK)")
+	viper.BindPFlag("noHealthCheck", namespaceCmd.Flags().Lookup("no-health-check"))
+	namespaceCmd.Flags().Bool("watch", false, "select namespace from a list updated whenever namespaces change, printing it without prompt, until idle for timeout")
+	namespaceCmd.Flags().Bool("wait", false, "wait for namespace to be active before switching to it")
+	namespaceCreateCmd.Flags().Bool("wait", false, "wait for created namespace to be active before switching to it")
+	namespaceCmd.Flags().String("sort", "name", "sort namespaces by name or recent for last selected first (KUBESWITCH_SORT)")
+	viper.BindPFlag("sort", namespaceCmd.Flags().Lookup("sort"))
+	namespaceCmd.Flags().StringSlice("across", nil, "select namespace across contexts, switching to its context")
+	namespaceCmd.Flags().BoolP("yes", "y", false, "switch to contexts in confirm key without confirmation with --across")
+	namespaceCmd.Flags().Int("concurrency", 4, "contexts to fetch namespaces from at once with --across (KUBESWITCH_CONCURRENCY)")
+	viper.BindPFlag("concurrency", namespaceCmd.Flags().Lookup("concurrency"))
+	namespaceCmd.Flags().Int64("page-size", 500, "namespaces fetched per request (KUBESWITCH_PAGESIZE)")
+	viper.BindPFlag("pageSize", namespaceCmd.Flags().Lookup("page-size"))
+
+	// Flags of talking to Kubernetes also available to subcommands.
+	namespaceCmd.PersistentFlags().Bool("insecure", false, "skip verifying certificates of clusters when listing namespaces (KUBESWITCH_INSECURE)")
+	viper.BindPFlag("insecure", namespaceCmd.PersistentFlags().Lookup("insecure"))
+	namespaceCmd.PersistentFlags().String("as", "", "user to impersonate when listing namespaces")
+	namespaceCmd.PersistentFlags().StringSlice("as-group", nil, "group to impersonate when listing namespaces, can be repeated")
+	namespaceCmd.PersistentFlags().Int("retry-attempts", 3, "attempts of namespace requests failing with transient errors (KUBESWITCH_RETRY_ATTEMPTS)")
+	viper.BindPFlag("retry.attempts", namespaceCmd.PersistentFlags().Lookup("retry-attempts"))
+	viper.BindEnv("retry.attempts", "KUBESWITCH_RETRY_ATTEMPTS")
+	namespaceCmd.PersistentFlags().Duration("retry-delay", 500*time.Millisecond, "delay before first retry, doubled for each retry (KUBESWITCH_RETRY_DELAY)")
+	viper.BindPFlag("retry.delay", namespaceCmd.PersistentFlags().Lookup("retry-delay"))
+	viper.BindEnv("retry.delay", "KUBESWITCH_RETRY_DELAY")
 }
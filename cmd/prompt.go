@@ -0,0 +1,67 @@
+/*
+Copyright © 2020 Chung Tran <chung.k.tran@gmail.com>
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/spf13/cobra"
+)
+
+const (
+	// promptColor is the color of context in prompt.
+	promptColor = "\033[36m"
+
+	// promptNsColor is the color of namespace in prompt.
+	promptNsColor = "\033[33m"
+
+	// promptReset resets color in prompt.
+	promptReset = "\033[0m"
+)
+
+// promptCmd represents the prompt command that prints current context and
+// namespace for shell prompts. It only reads config so it doesn't slow down
+// the prompt with Kubernetes API requests.
+var promptCmd = &cobra.Command{
+	Use:   "prompt",
+	Short: "Print current context and namespace for shell prompt",
+	Args:  cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		ks := newKubeswitch()
+		color, _ := cmd.Flags().GetBool("color")
+		fmt.Println(promptString(ks.CurrentContext(), ks.CurrentNamespace(), color))
+	},
+}
+
+// promptString returns ctx and ns formatted as `ctx:ns` for shell prompt.
+func promptString(ctx, ns string, color bool) string {
+	// Context without default namespace uses the default namespace.
+	if ns == "" {
+		ns = "default"
+	}
+
+	if color {
+		return promptColor + ctx + promptReset + ":" + promptNsColor + ns + promptReset
+	}
+	return ctx + ":" + ns
+}
+
+func init() {
+	rootCmd.AddCommand(promptCmd)
+
+	// Local flags only available to this command.
+	promptCmd.Flags().Bool("color", false, "color context and namespace")
+}
@@ -17,6 +17,9 @@ package cmd
 
 import (
 	"fmt"
+	"math"
+	"strconv"
+	"strings"
 
 	"github.com/spf13/cobra"
 	"github.com/spf13/viper"
@@ -28,18 +31,96 @@ var purgeCmd = &cobra.Command{
 	Use:   "purge",
 	Short: "Purge temporary session files",
 	Run: func(cmd *cobra.Command, args []string) {
-		days := viper.GetInt("purge.days")
-		fmt.Printf("purging temporary session files older than %d day(s) ...\n", days)
-		kubeswitch.Purge(days)
-		fmt.Println("done")
+		// Delete all session files after user confirms.
+		if all, _ := cmd.Flags().GetBool("all"); all {
+			if yes, _ := cmd.Flags().GetBool("yes"); !yes {
+				if !interactive() {
+					failCode("purging all session files requires confirmation, use --yes", exitUsage)
+				}
+				if err := confirmPrompt("Delete all temporary session files"); err != nil {
+					fail(err)
+				}
+			}
+
+			info("purging all temporary session files ...")
+			if err := kubeswitch.PurgeAll(); err != nil {
+				fail(err)
+			}
+			info("done")
+			return
+		}
+
+		// Purge by age when days is set or no other criterion is given.
+		maxSize := viper.GetString("purge.maxSize")
+		if viper.IsSet("purge.days") || (maxSize == "" && !viper.IsSet("purge.keep")) {
+			days := viper.GetInt("purge.days")
+			info("purging temporary session files older than %d day(s) ...", days)
+			if err := kubeswitch.Purge(days); err != nil {
+				fail(err)
+			}
+		}
+
+		// Cap total size of remaining session files.
+		if maxSize != "" {
+			limit, err := parseSize(maxSize)
+			if err != nil {
+				failCode(err, exitUsage)
+			}
+			info("purging oldest temporary session files over %s ...", maxSize)
+			if err := kubeswitch.PurgeBySize(limit); err != nil {
+				fail(err)
+			}
+		}
+
+		// Keep only the most recent session files.
+		if viper.IsSet("purge.keep") {
+			keep := viper.GetInt("purge.keep")
+			if keep < 0 {
+				failCode(fmt.Sprintf("invalid keep %d, must be at least 0", keep), exitUsage)
+			}
+			info("purging temporary session files except %d most recent ...", keep)
+			if err := kubeswitch.PurgeKeep(keep); err != nil {
+				fail(err)
+			}
+		}
+		info("done")
 	},
 }
 
+// sizeUnits are multipliers of size units accepted by parseSize.
+var sizeUnits = map[string]int64{"": 1, "B": 1, "KB": 1 << 10, "MB": 1 << 20, "GB": 1 << 30}
+
+// parseSize returns bytes of size such as 500KB, 50MB, or 1GB.
+// Units are case-insensitive and powers of 1024.
+func parseSize(size string) (int64, error) {
+	s := strings.ToUpper(strings.TrimSpace(size))
+	num := strings.TrimRightFunc(s, func(r rune) bool { return r < '0' || r > '9' })
+	unit, ok := sizeUnits[strings.TrimSpace(s[len(num):])]
+
+	n, err := strconv.ParseInt(num, 10, 64)
+	if err != nil || !ok || n < 0 {
+		return 0, fmt.Errorf("invalid size %s, must be a number with unit B, KB, MB, or GB", size)
+	}
+	if n > math.MaxInt64/unit {
+		return 0, fmt.Errorf("invalid size %s, too large", size)
+	}
+
+	return n * unit, nil
+}
+
 func init() {
 	rootCmd.AddCommand(purgeCmd)
 
 	// Local flags only available to this command.
+	purgeCmd.Flags().Bool("all", false, "purge all session files regardless of age")
+	purgeCmd.Flags().BoolP("yes", "y", false, "purge all session files without confirmation")
 	purgeCmd.Flags().IntP("days", "d", 2, "days to rentain (KUBESWITCH_PURGE_DAYS)")
 	viper.BindPFlag("purge.days", purgeCmd.Flags().Lookup("days"))
 	viper.BindEnv("purge.days", "KUBESWITCH_PURGE_DAYS")
+	purgeCmd.Flags().String("max-size", "", "maximum total size of session files, e.g. 50MB (KUBESWITCH_PURGE_MAXSIZE)")
+	viper.BindPFlag("purge.maxSize", purgeCmd.Flags().Lookup("max-size"))
+	viper.BindEnv("purge.maxSize", "KUBESWITCH_PURGE_MAXSIZE")
+	purgeCmd.Flags().Int("keep", 0, "number of most recent session files to keep (KUBESWITCH_PURGE_KEEP)")
+	viper.BindPFlag("purge.keep", purgeCmd.Flags().Lookup("keep"))
+	viper.BindEnv("purge.keep", "KUBESWITCH_PURGE_KEEP")
 }
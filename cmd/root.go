@@ -16,9 +16,20 @@ limitations under the License.
 package cmd
 
 import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
+	"io/fs"
 	"os"
+	"regexp"
+	"sort"
 	"strings"
+	"sync"
+	"text/template"
+	"time"
 
 	"path/filepath"
 
@@ -26,26 +37,103 @@ import (
 	homedir "github.com/mitchellh/go-homedir"
 	"github.com/spf13/cobra"
 	"github.com/spf13/viper"
+	"k8s.io/client-go/tools/clientcmd"
 	"github.com/ckt114/kubeswitch/kubeswitch"
 )
 
 const (
-	defaultCfg = "$HOME/.kubeswitch.yaml"
+	defaultCfg   = "$HOME/.kubeswitch.yaml"
+	defaultLabel = "Select %s. / to search"
+	redacted     = "REDACTED"
+	pluginPrefix = "kubectl-"
+
+	// clearScreen moves cursor to top left and clears the terminal.
+	clearScreen = "\033[H\033[2J"
+
+	// doneOption ends selecting several items in selection prompt.
+	doneOption = "[done]"
+
+	// favoriteMarker marks favorite contexts in selection prompt.
+	favoriteMarker = "* "
+)
+
+// Exit codes for different classes of failures.
+const (
+	exitError     = 1
+	exitUsage     = 2
+	exitConfig    = 3
+	exitCancelled = 130
 )
 
+// configExts are extensions of Kubernetes config files loaded from folders.
+var configExts = []string{".yaml", ".yml", ".json"}
+
+// defaultPrecedence is the default order of Kubernetes config sources.
+var defaultPrecedence = []string{"kubeconfig", "env", "configs"}
+
 // Version will automatically be set to latest git tagged version.
 var Version = "v0.0.0"
 
 var (
+	// configFiles are the Kubernetes config paths assembled into KUBECONFIG
+	// including ones that don't exist.
+	configFiles []string
+
 	// list prints result one per line.
 	list = func(data *[]string) {
 		fmt.Println(strings.Join(*data, "\n"))
 	}
 
-	// fail prints error message and exit.
+	// info prints informational message unless quiet output is enabled.
+	info = func(format string, a ...interface{}) {
+		if !viper.GetBool("quiet") {
+			fmt.Printf(format+"\n", a...)
+		}
+	}
+
+	// warn prints warning message to stderr unless quiet output is enabled
+	// so that it's not mixed with output parsed by shell completion.
+	warn = func(format string, a ...interface{}) {
+		if !viper.GetBool("quiet") {
+			fmt.Fprintf(os.Stderr, "WARN: "+format+"\n", a...)
+		}
+	}
+
+	// verbose prints message to stderr when verbose output is enabled.
+	verbose = func(format string, a ...interface{}) {
+		logEvent("log", nil, format, a...)
+	}
+
+	// logOutput is where verbose messages are printed.
+	logOutput io.Writer = os.Stderr
+
+	// interactive returns true if stdin and prompt output are terminals
+	// that the selection prompt can be used with.
+	interactive = func() bool {
+		return isTerminal(os.Stdin) && isTerminal(promptOutput())
+	}
+
+	// fail prints error message to stderr and exit with code for the class of err.
 	fail = func(err interface{}) {
-		fmt.Println(err)
-		os.Exit(1)
+		// Exit quietly with status of session shell since it's not an error
+		// of kubeswitch.
+		var exitErr *kubeswitch.ShellExitError
+		if e, ok := err.(error); ok && errors.As(e, &exitErr) {
+			os.Exit(exitCode(err))
+		}
+
+		failCode(err, exitCode(err))
+	}
+
+	// failCode prints error message to stderr and exit with code.
+	failCode = func(err interface{}, code int) {
+		// Exit quietly when user cancels the selection prompt.
+		if code == exitCancelled {
+			os.Exit(code)
+		}
+
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(code)
 	}
 )
 
@@ -56,21 +144,75 @@ var rootCmd = &cobra.Command{
 	Run: func(cmd *cobra.Command, args []string) {
 		if viper.GetBool("version") {
 			fmt.Println(Version)
+		} else if viper.GetBool("printKubeconfigPath") {
+			path, err := kubeconfigPath()
+			if err != nil {
+				fail(err)
+			}
+			fmt.Println(path)
+			clip, _ := cmd.Flags().GetBool("copy-to-clipboard")
+			copyOutput(clip, path)
 		} else if viper.GetBool("debug") {
 			fmt.Println("KUBECONFIG:", os.Getenv(kubeswitch.EnvVarConfig))
 			fmt.Println("Kubeswitch config:", viper.ConfigFileUsed())
-			fmt.Printf("Config Values: %+v\n", viper.AllSettings())
+			fmt.Println("Session depth:", kubeswitch.Depth())
+			fmt.Printf("Config Values: %+v\n", redact(viper.AllSettings()))
 		} else {
 			cmd.Help()
 		}
 	},
 }
 
+// setupPlugin names the command tree after the kubectl plugin when the binary
+// is invoked as one. kubectl runs binaries named `kubectl-<name>` on PATH as
+// `kubectl <name>`, so installing or linking the binary as `kubectl-switch`
+// (e.g. /usr/local/bin/kubectl-switch) makes `kubectl switch ctx` work.
+func setupPlugin(arg0 string) {
+	name := filepath.Base(arg0)
+	if !strings.HasPrefix(name, pluginPrefix) {
+		return
+	}
+
+	// kubectl maps dashes in plugin commands to underscores in binary names.
+	name = "kubectl " + strings.ReplaceAll(strings.TrimPrefix(name, pluginPrefix), "_", "-")
+	if rootCmd.Annotations == nil {
+		rootCmd.Annotations = map[string]string{}
+	}
+	rootCmd.Annotations[cobra.CommandDisplayNameAnnotation] = name
+}
+
+// exitCode returns exit code for the class of err.
+func exitCode(err interface{}) int {
+	e, ok := err.(error)
+	if !ok {
+		return exitError
+	}
+
+	switch {
+	case e == promptui.ErrInterrupt || e == promptui.ErrAbort || e == promptui.ErrEOF:
+		return exitCancelled
+	case errors.Is(e, kubeswitch.ErrInvalidContext) || errors.Is(e, kubeswitch.ErrInvalidNamespace):
+		return exitUsage
+	case errors.Is(e, kubeswitch.ErrNoKubeconfig) || errors.Is(e, kubeswitch.ErrMalformedConfig):
+		return exitConfig
+	}
+
+	// Pass on exit status of session shell.
+	var exitErr *kubeswitch.ShellExitError
+	if errors.As(e, &exitErr) && exitErr.Code > 0 {
+		return exitErr.Code
+	}
+	return exitError
+}
+
 // Execute adds all child commands to the root command and sets flags appropriately.
 // This is called by main.main(). It only needs to happen once to the rootCmd.
 func Execute() {
+	setupPlugin(os.Args[0])
+
+	// Commands only return errors for invalid flags and arguments.
 	if err := rootCmd.Execute(); err != nil {
-		fail(err)
+		failCode(err, exitUsage)
 	}
 }
 
@@ -81,12 +223,30 @@ func init() {
 	rootCmd.PersistentFlags().StringP("config", "c", defaultCfg, "kubeswitch config (KUBESWITCH_CONFIG)")
 	rootCmd.PersistentFlags().BoolP("no-config", "C", false, "don't use kubeswitch config (KUBESWITCH_NOCONFIG)")
 	rootCmd.PersistentFlags().StringP("kubeconfig", "k", "", "kubernetes config to read (KUBESWITCH_KUBECONFIG)")
+	rootCmd.PersistentFlags().String("profile", "", "profile of configs to use instead of configs key (KUBESWITCH_PROFILE)")
+	rootCmd.PersistentFlags().Bool("kubeconfig-exclusive", false, "only use kubeconfig, ignoring KUBECONFIG and configs (KUBESWITCH_KUBECONFIGEXCLUSIVE)")
 	rootCmd.PersistentFlags().IntP("prompt-size", "p", 10, "selection prompt size (KUBESWITCH_PROMPTSIZE)")
 	rootCmd.PersistentFlags().BoolP("no-prompt", "P", false, "disable selection prompt (KUBESWITCH_NOPROMPT)")
+	rootCmd.PersistentFlags().BoolP("exact", "e", false, "require exact context or namespace name (KUBESWITCH_EXACT)")
+	rootCmd.PersistentFlags().Bool("search", false, "start selection prompt in search mode (KUBESWITCH_PROMPT_STARTINSEARCH)")
+	rootCmd.PersistentFlags().Bool("print-export", false, "print env var exports instead of running a shell (KUBESWITCH_PRINTEXPORT)")
+	rootCmd.PersistentFlags().Bool("cleanup-on-exit", false, "delete session file when session shell exits (KUBESWITCH_CLEANUPONEXIT)")
+	rootCmd.PersistentFlags().Bool("session-by-context", false, "name session files after their context to reuse them (KUBESWITCH_SESSIONBYCONTEXT)")
+	rootCmd.PersistentFlags().Bool("no-shell", false, "write session file without running a shell (KUBESWITCH_NOSHELL)")
+	rootCmd.PersistentFlags().Bool("no-session", false, "change kubernetes config files in place without a session (KUBESWITCH_NOSESSION)")
+	rootCmd.PersistentFlags().StringP("shell", "s", "", "shell to run for new sessions (KUBESWITCH_SHELL)")
+	rootCmd.PersistentFlags().Int("max-depth", 5, "maximum nested session shells, 0 for unlimited (KUBESWITCH_MAXDEPTH)")
+	rootCmd.PersistentFlags().Bool("no-flatten", false, "keep references to certificate files instead of embedding them (KUBESWITCH_NOFLATTEN)")
+	rootCmd.PersistentFlags().BoolP("verbose", "V", false, "print verbose info (KUBESWITCH_VERBOSE)")
+	rootCmd.PersistentFlags().String("log-format", "text", "format of verbose messages, text or json (KUBESWITCH_LOGFORMAT)")
+	rootCmd.PersistentFlags().BoolP("quiet", "q", false, "don't print informational messages (KUBESWITCH_QUIET)")
+	rootCmd.PersistentFlags().DurationP("timeout", "t", 10*time.Second, "kubernetes API request timeout (KUBESWITCH_TIMEOUT)")
 
 	// Local flags only available to this command.
 	rootCmd.Flags().BoolP("version", "v", false, "print version")
 	rootCmd.Flags().BoolP("debug", "d", false, "print debug info")
+	rootCmd.Flags().Bool("print-kubeconfig-path", false, "print path of kubernetes config in use")
+	rootCmd.Flags().Bool("copy-to-clipboard", false, "also copy printed kubernetes config path to clipboard")
 }
 
 // initConfig reads in config file and ENV variables if set.
@@ -98,11 +258,34 @@ func initConfig() {
 	viper.BindPFlag("config", rootCmd.Flags().Lookup("config"))
 	viper.BindPFlag("noConfig", rootCmd.Flags().Lookup("no-config"))
 	viper.BindPFlag("kubeConfig", rootCmd.Flags().Lookup("kubeconfig"))
+	viper.BindPFlag("profile", rootCmd.Flags().Lookup("profile"))
+	viper.BindPFlag("kubeConfigExclusive", rootCmd.Flags().Lookup("kubeconfig-exclusive"))
 	viper.BindPFlag("promptSize", rootCmd.Flags().Lookup("prompt-size"))
 	viper.BindPFlag("noPrompt", rootCmd.Flags().Lookup("no-prompt"))
+	viper.BindPFlag("exact", rootCmd.Flags().Lookup("exact"))
+	viper.BindPFlag("prompt.startInSearch", rootCmd.Flags().Lookup("search"))
+	viper.BindEnv("prompt.startInSearch", "KUBESWITCH_PROMPT_STARTINSEARCH")
+	viper.BindPFlag("printExport", rootCmd.Flags().Lookup("print-export"))
+	viper.BindPFlag("cleanupOnExit", rootCmd.Flags().Lookup("cleanup-on-exit"))
+	viper.BindPFlag("sessionByContext", rootCmd.Flags().Lookup("session-by-context"))
+	viper.BindPFlag("noShell", rootCmd.Flags().Lookup("no-shell"))
+	viper.BindPFlag("noSession", rootCmd.Flags().Lookup("no-session"))
+	viper.BindPFlag("shell", rootCmd.Flags().Lookup("shell"))
+	viper.BindPFlag("maxDepth", rootCmd.Flags().Lookup("max-depth"))
+	viper.BindPFlag("noFlatten", rootCmd.Flags().Lookup("no-flatten"))
+	viper.BindPFlag("verbose", rootCmd.Flags().Lookup("verbose"))
+	viper.BindPFlag("logFormat", rootCmd.Flags().Lookup("log-format"))
+	viper.BindPFlag("quiet", rootCmd.Flags().Lookup("quiet"))
+	viper.BindPFlag("timeout", rootCmd.Flags().Lookup("timeout"))
 
 	viper.BindPFlag("version", rootCmd.Flags().Lookup("version"))
 	viper.BindPFlag("debug", rootCmd.Flags().Lookup("debug"))
+	viper.BindPFlag("printKubeconfigPath", rootCmd.Flags().Lookup("print-kubeconfig-path"))
+
+	// Print verbose messages from Kubeswitch.
+	kubeswitch.Logf = verbose
+	kubeswitch.Warnf = warn
+	kubeswitch.LogEventf = logEvent
 
 	// Only read Kubeswitch config file if `noConfig` is false.
 	if !viper.GetBool("noConfig") {
@@ -112,17 +295,84 @@ func initConfig() {
 		// Read Kubeswitch config if file exists.
 		if _, err := os.Stat(viper.ConfigFileUsed()); err == nil {
 			if err := viper.ReadInConfig(); err != nil {
-				fail(fmt.Sprintln(viper.ConfigFileUsed(), ":", err))
+				failCode(fmt.Sprintln(viper.ConfigFileUsed(), ":", err), exitConfig)
 			}
 		} else {
-			fmt.Printf("WARN: Config file \"%s\" not exists\n", viper.ConfigFileUsed())
+			warn("Config file \"%s\" not exists", viper.ConfigFileUsed())
 		}
 	}
 
+	// Validate log format after config file since it can be set there.
+	if f := viper.GetString("logFormat"); f != "text" && f != "json" {
+		failCode(fmt.Sprintf("invalid log format %s, must be one of: text, json", f), exitUsage)
+	}
+
+	// Validate prompt templates early rather than when prompting.
+	if _, err := selectTemplates(); err != nil {
+		failCode(err, exitConfig)
+	}
+
 	// Setup KUBECONFIG from flags, env vars, and config file.
 	if err := setupKubeEnvVar(); err != nil {
+		failCode(err, exitConfig)
+	}
+}
+
+// newKubeswitch returns an instance of Kubeswitch configured from flags,
+// env vars, and config file.
+func newKubeswitch() *kubeswitch.Kubeswitch {
+	ks, err := kubeswitch.NewWithOptions(kubeswitch.Options{
+		NoFlatten: viper.GetBool("noFlatten"),
+	})
+	if errors.Is(err, kubeswitch.ErrNoKubeconfig) {
+		fail(fmt.Errorf("%w, use --kubeconfig, KUBECONFIG env var, or configs key to set Kubernetes configs", err))
+	} else if err != nil {
 		fail(err)
 	}
+	ks.NoRestore = viper.GetBool("noRestore")
+	ks.PrintExport = viper.GetBool("printExport")
+	ks.NoShell = viper.GetBool("noShell")
+	ks.CleanupOnExit = viper.GetBool("cleanupOnExit")
+	ks.SessionByContext = viper.GetBool("sessionByContext")
+	ks.Shell = viper.GetString("shell")
+	ks.MaxDepth = viper.GetInt("maxDepth")
+	ks.NoSession = viper.GetBool("noSession")
+	ks.Hooks = kubeswitch.Hooks{
+		PreContext:    viper.GetString("hooks.preContext"),
+		PostContext:   viper.GetString("hooks.postContext"),
+		PostNamespace: viper.GetString("hooks.postNamespace"),
+	}
+
+	return ks
+}
+
+// apiContext returns a context that times out after `timeout` for
+// Kubernetes API requests.
+func apiContext() (context.Context, context.CancelFunc) {
+	return context.WithTimeout(context.Background(), viper.GetDuration("timeout"))
+}
+
+// apiError returns a clearer error than err when ctx has timed out.
+func apiError(ctx context.Context, err error) error {
+	if ctx.Err() == context.DeadlineExceeded {
+		return fmt.Errorf("timed out after %s, cluster may be unreachable; use --timeout to wait longer", viper.GetDuration("timeout"))
+	}
+	return err
+}
+
+// kubeconfigPath returns the Kubernetes config path in use, which is the
+// session file when in a Kubeswitch session. Otherwise, it's the configs
+// assembled into KUBECONFIG or the default kube config.
+func kubeconfigPath() (string, error) {
+	if path := os.Getenv(kubeswitch.EnvVarConfig); path != "" {
+		return path, nil
+	}
+
+	if _, err := os.Stat(clientcmd.RecommendedHomeFile); err == nil {
+		return clientcmd.RecommendedHomeFile, nil
+	}
+
+	return "", fmt.Errorf("no kubernetes config found")
 }
 
 // setupKubeEnvVar finds all the Kubernetes configs defined in Kubeswitch config file
@@ -130,80 +380,722 @@ func initConfig() {
 // This is so that clientcmd can read multiple config at once.
 func setupKubeEnvVar() error {
 	if !kubeswitch.IsActive() {
-		var configs []string
-
-		// Add kubeConfig into list of configs.
-		cfg, err := homedir.Expand(os.ExpandEnv(viper.GetString("kubeConfig")))
+		candidates, err := kubeConfigCandidates()
 		if err != nil {
 			return err
 		}
-		configs = append(configs, cfg)
+		configFiles = candidates
 
-		// Add KUBECONFIG into list of configs if defined.
-		kConfig, err := homedir.Expand(os.ExpandEnv(os.Getenv(kubeswitch.EnvVarConfig)))
-		if err != nil {
+		// Only use configs that exist.
+		configs := removeMissing(candidates)
+		verbose("Kubernetes configs: %s", strings.Join(configs, ", "))
+
+		// Set KUBECONFIG to list of configs separated by colon.
+		if err := os.Setenv(kubeswitch.EnvVarConfig, strings.Join(configs, ":")); err != nil {
 			return err
 		}
-		configs = append(configs, kConfig)
+	}
+
+	return nil
+}
+
+// kubeConfigCandidates returns Kubernetes config paths from `--kubeconfig` flag,
+// KUBECONFIG env var, and `configs` key ordered by `precedence` key. Configs
+// earlier in the list take precedence when clientcmd merges them. Paths are
+// returned whether or not they exist.
+func kubeConfigCandidates() ([]string, error) {
+	sources := map[string][]string{"kubeconfig": nil, "env": nil, "configs": nil}
+
+	// Add kubeConfig into list of configs.
+	cfg, err := homedir.Expand(os.ExpandEnv(viper.GetString("kubeConfig")))
+	if err != nil {
+		return nil, err
+	}
+	sources["kubeconfig"] = []string{cfg}
 
-		// Get list of files matching patterns in `configs` key.
-		for _, path := range viper.GetStringSlice("configs") {
-			absPath, _ := homedir.Expand(os.ExpandEnv(path))
-			files, _ := filepath.Glob(absPath)
-			configs = append(configs, files...)
+	// Only use `--kubeconfig` flag when it's exclusive.
+	if viper.GetBool("kubeConfigExclusive") {
+		if cfg == "" {
+			return nil, fmt.Errorf("kubeconfig is required when kubeconfig-exclusive is set")
 		}
+		verbose("Kubernetes config %s is used exclusively", cfg)
+		return []string{cfg}, nil
+	}
 
-		// Remove duplicate config paths from `configs`.
-		configs = removeDuplicates(configs)
+	// Add each path in KUBECONFIG into list of configs if defined.
+	for _, path := range filepath.SplitList(os.Getenv(kubeswitch.EnvVarConfig)) {
+		kConfig, err := homedir.Expand(os.ExpandEnv(path))
+		if err != nil {
+			return nil, err
+		}
+		sources["env"] = append(sources["env"], expandDir(kConfig)...)
+	}
 
-		// Set KUBECONFIG to list of configs separated by colon.
-		if err := os.Setenv(kubeswitch.EnvVarConfig, strings.Join(configs, ":")); err != nil {
+	// Get list of files matching patterns in `configs` key of profile. Relative
+	// patterns are resolved from Kubeswitch config's folder if `relativeConfigs` is set.
+	patterns, err := profileConfigs()
+	if err != nil {
+		return nil, err
+	}
+	for _, path := range patterns {
+		absPath, _ := homedir.Expand(os.ExpandEnv(path))
+		if viper.GetBool("relativeConfigs") && !filepath.IsAbs(absPath) && viper.ConfigFileUsed() != "" {
+			absPath = filepath.Join(filepath.Dir(viper.ConfigFileUsed()), absPath)
+		}
+		files, _ := glob(absPath)
+		for _, file := range files {
+			sources["configs"] = append(sources["configs"], expandDir(file)...)
+		}
+	}
+
+	// Order configs by precedence of their sources.
+	precedence := viper.GetStringSlice("precedence")
+	if len(precedence) == 0 {
+		precedence = defaultPrecedence
+	}
+
+	var configs []string
+	for _, src := range precedence {
+		paths, ok := sources[src]
+		if !ok {
+			return nil, fmt.Errorf("invalid precedence %s, must be one of: %s", src, strings.Join(defaultPrecedence, ", "))
+		}
+		configs = append(configs, paths...)
+	}
+
+	// Remove duplicate config paths from `configs`.
+	verbose("Kubernetes config precedence: %s", strings.Join(precedence, ", "))
+	return removeDuplicates(configs), nil
+}
+
+// logEvent prints message of event to logOutput when verbose output is enabled.
+// It's printed as a JSON line with fields of the event when `logFormat` is json.
+func logEvent(event string, fields map[string]interface{}, format string, a ...interface{}) {
+	if !viper.GetBool("verbose") {
+		return
+	}
+
+	msg := fmt.Sprintf(format, a...)
+	if viper.GetString("logFormat") != "json" {
+		fmt.Fprintln(logOutput, msg)
+		return
+	}
+
+	entry := map[string]interface{}{}
+	for k, v := range fields {
+		entry[k] = v
+	}
+	entry["time"] = time.Now().Format(time.RFC3339Nano)
+	entry["event"] = event
+	entry["message"] = msg
+
+	data, err := json.Marshal(entry)
+	if err != nil {
+		fmt.Fprintln(logOutput, msg)
+		return
+	}
+	fmt.Fprintln(logOutput, string(data))
+}
+
+// glob returns paths matching pattern like filepath.Glob. A `**` path element
+// also matches any number of nested folders, in which case only files are
+// returned, sorted by path.
+func glob(pattern string) ([]string, error) {
+	parts := strings.Split(filepath.ToSlash(pattern), "/")
+	i := 0
+	for i < len(parts) && parts[i] != "**" {
+		i++
+	}
+	if i == len(parts) {
+		return filepath.Glob(pattern)
+	}
+
+	// Walk folders matching the pattern before the first `**`.
+	base := filepath.FromSlash(strings.Join(parts[:i], "/"))
+	if base == "" && strings.HasPrefix(pattern, "/") {
+		base = "/"
+	} else if base == "" {
+		base = "."
+	}
+	roots, err := filepath.Glob(base)
+	if err != nil {
+		return nil, err
+	}
+
+	var files []string
+	for _, root := range roots {
+		filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+			// Skip folders that can't be read and only match files.
+			if err != nil || d.IsDir() {
+				return nil
+			}
+			rel, _ := filepath.Rel(root, path)
+			if matchParts(parts[i:], strings.Split(filepath.ToSlash(rel), "/")) {
+				files = append(files, path)
+			}
+			return nil
+		})
+	}
+	sort.Strings(files)
+
+	return files, nil
+}
+
+// matchParts returns true if path elements in parts match pattern elements,
+// where `**` matches zero or more elements.
+func matchParts(pattern, parts []string) bool {
+	if len(pattern) == 0 {
+		return len(parts) == 0
+	}
+
+	if pattern[0] == "**" {
+		for i := 0; i <= len(parts); i++ {
+			if matchParts(pattern[1:], parts[i:]) {
+				return true
+			}
+		}
+		return false
+	}
+
+	if len(parts) == 0 {
+		return false
+	}
+	ok, _ := filepath.Match(pattern[0], parts[0])
+	return ok && matchParts(pattern[1:], parts[1:])
+}
+
+// expandDir returns config files directly inside path sorted by name if path
+// is a folder. Only files with extensions in configExts are returned. Any
+// other path is returned as is.
+func expandDir(path string) []string {
+	if info, err := os.Stat(path); err != nil || !info.IsDir() {
+		return []string{path}
+	}
+
+	var files []string
+	for _, ext := range configExts {
+		matches, _ := filepath.Glob(filepath.Join(path, "*"+ext))
+		files = append(files, matches...)
+	}
+	sort.Strings(files)
+
+	verbose("Kubernetes config folder %s has configs: %s", path, strings.Join(files, ", "))
+	return files
+}
+
+// profileConfigs returns path patterns of `configs` key under `profiles` for
+// the selected profile. Top-level `configs` key is the default profile.
+func profileConfigs() ([]string, error) {
+	profile := viper.GetString("profile")
+	if profile == "" {
+		return viper.GetStringSlice("configs"), nil
+	}
+
+	key := "profiles." + profile
+	if !viper.IsSet(key) {
+		return nil, fmt.Errorf("invalid profile %s, not defined in profiles key", profile)
+	}
+	verbose("Using profile %s", profile)
+	return viper.GetStringSlice(key + ".configs"), nil
+}
+
+// redact returns a copy of data with values of sensitive keys such as tokens,
+// passwords, and client keys masked. Use it on anything printed for debugging.
+func redact(data map[string]interface{}) map[string]interface{} {
+	result := map[string]interface{}{}
+	for key, value := range data {
+		if isSensitive(key) {
+			result[key] = redacted
+			continue
+		}
+		result[key] = redactValue(value)
+	}
+	return result
+}
+
+// redactValue redacts maps nested in value.
+func redactValue(value interface{}) interface{} {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		return redact(v)
+	case []interface{}:
+		items := make([]interface{}, len(v))
+		for i := range v {
+			items[i] = redactValue(v[i])
+		}
+		return items
+	default:
+		return v
+	}
+}
+
+// isSensitive returns true if key holds a secret.
+func isSensitive(key string) bool {
+	key = strings.ToLower(key)
+	for _, s := range []string{"token", "password", "secret", "client-key-data"} {
+		if strings.Contains(key, s) {
+			return true
+		}
+	}
+	return false
+}
+
+// removeDuplicates returns items of s in first-seen order with duplicates
+// and empty strings removed. Order is preserved since clientcmd resolves
+// name collisions by the order of files in KUBECONFIG.
+func removeDuplicates(s []string) []string {
+	seen := map[string]bool{}
+
+	result := []string{}
+	for _, v := range s {
+		// Skip empty paths from unset flags and env vars.
+		if v == "" || seen[v] {
+			continue
+		}
+		seen[v] = true
+		result = append(result, v)
+	}
+	return result
+}
+
+// noPrompt returns true if items should be listed instead of prompting user
+// to select one, either because `noPrompt` is set or there is no terminal
+// to prompt on such as when output is piped or running in CI.
+func noPrompt() bool {
+	return viper.GetBool("noPrompt") || !interactive()
+}
+
+// writeOutput writes config of ks to file at output, or to stdout if output
+// is empty so that it can be piped.
+func writeOutput(ks *kubeswitch.Kubeswitch, output string) error {
+	if output != "" {
+		return ks.WriteToFile(output)
+	}
+
+	data, err := ks.Bytes()
+	if err != nil {
+		return err
+	}
+	_, err = os.Stdout.Write(data)
+	return err
+}
+
+// updateConfig sets key in Kubeswitch config file to the value returned by
+// update, which is passed the file's current content.
+func updateConfig(key string, update func(cfg *viper.Viper) interface{}) error {
+	if viper.GetBool("noConfig") {
+		return fmt.Errorf("kubeswitch config is required to update %s", key)
+	}
+
+	// Use a separate instance so only the file's content is written back.
+	cfg := viper.New()
+	cfg.SetConfigFile(viper.ConfigFileUsed())
+	if _, err := os.Stat(viper.ConfigFileUsed()); err == nil {
+		if err := cfg.ReadInConfig(); err != nil {
 			return err
 		}
 	}
 
+	value := update(cfg)
+	cfg.Set(key, value)
+	viper.Set(key, value)
+
+	return cfg.WriteConfig()
+}
+
+// promptOutput returns the file prompts are drawn on. Stderr is used with
+// `printExport` so that prompts aren't captured by `eval "$(...)"`.
+func promptOutput() *os.File {
+	if viper.GetBool("printExport") {
+		return os.Stderr
+	}
+	return os.Stdout
+}
+
+// nopCloser is a writer for prompts that isn't closed with the prompt.
+type nopCloser struct {
+	io.Writer
+}
+
+// Close does nothing.
+func (nopCloser) Close() error {
 	return nil
 }
 
-func removeDuplicates(s []string) []string {
-	items := map[string]bool{}
+// isTerminal returns true if f is a terminal rather than a pipe or file.
+func isTerminal(f *os.File) bool {
+	fi, err := f.Stat()
+	return err == nil && fi.Mode()&os.ModeCharDevice != 0
+}
+
+// removeMissing returns items of s that exist on disk.
+func removeMissing(s []string) []string {
+	result := []string{}
+	for _, v := range s {
+		if _, err := os.Stat(v); err == nil {
+			result = append(result, v)
+		}
+	}
+	return result
+}
+
+// resolveName returns the item in names matching name. Unless `exact` is set,
+// a name that is not an exact match resolves to the only item prefixed with it,
+// or the only item containing it when no items are prefixed with it.
+// Name is returned as is when nothing matches so callers can validate it.
+func resolveName(kind, name string, names []string) (string, error) {
+	for _, n := range names {
+		if n == name {
+			return n, nil
+		}
+	}
 
-	// Create a map of all unique items.
-	for v := range s {
-		items[s[v]] = true
+	if viper.GetBool("exact") {
+		return name, nil
 	}
 
-	// Place all keys from the map into a slice.
+	// Find items prefixed with name, then items containing name.
+	matches := matchNames(names, name, strings.HasPrefix)
+	if len(matches) == 0 {
+		matches = matchNames(names, name, strings.Contains)
+	}
+
+	switch len(matches) {
+	case 0:
+		return name, nil
+	case 1:
+		return matches[0], nil
+	default:
+		return "", fmt.Errorf("ambiguous %s %s, matches: %s", kind, name, strings.Join(matches, ", "))
+	}
+}
+
+// matchNames returns items of names where match(item, name) is true.
+func matchNames(names []string, name string, match func(string, string) bool) []string {
 	result := []string{}
-	for key := range items {
-		result = append(result, key)
+	for _, n := range names {
+		if match(n, name) {
+			result = append(result, n)
+		}
 	}
 	return result
 }
 
+// filterItems returns items of data matching the regular expression pattern.
+// All items are returned when pattern is empty.
+func filterItems(data []string, pattern string) ([]string, error) {
+	if pattern == "" {
+		return data, nil
+	}
+
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return nil, fmt.Errorf("invalid filter %q: %v", pattern, err)
+	}
+
+	result := []string{}
+	for _, v := range data {
+		if re.MatchString(v) {
+			result = append(result, v)
+		}
+	}
+	return result, nil
+}
+
+// selectTemplates returns select prompt templates from `prompt.template`
+// config key. Nil is returned when not set so that promptui defaults are used.
+func selectTemplates() (*promptui.SelectTemplates, error) {
+	tpls := viper.GetStringMapString("prompt.template")
+	if len(tpls) == 0 {
+		return nil, nil
+	}
+
+	// Ensure each template parses before prompting.
+	for name, tpl := range tpls {
+		if _, err := template.New(name).Funcs(promptui.FuncMap).Parse(tpl); err != nil {
+			return nil, fmt.Errorf("invalid prompt template %s: %v", name, err)
+		}
+	}
+
+	return &promptui.SelectTemplates{
+		Label:    tpls["label"],
+		Active:   tpls["active"],
+		Inactive: tpls["inactive"],
+		Selected: tpls["selected"],
+		Details:  tpls["details"],
+	}, nil
+}
+
 func selectOption(kind string, data []string) (string, error) {
+	prompt, err := newSelect(kind, data)
+	if err != nil {
+		return "", err
+	}
+
+	// Prompt user to select item from list.
+	_, i, err := prompt.Run()
+	if err != nil {
+		return "", err
+	}
+
+	return i, nil
+}
+
+// newSelect returns the select prompt for user to pick an item of kind
+// from data.
+func newSelect(kind string, data []string) (*promptui.Select, error) {
 	// Function used for filtering result set.
 	searcher := func(input string, index int) bool {
 		name := data[index]
 		return strings.Contains(name, input)
 	}
 
+	// Use label from config if set. Any `%s` in label is replaced with kind.
+	label := viper.GetString("prompt.label")
+	if label == "" {
+		label = defaultLabel
+	}
+
+	templates, err := selectTemplates()
+	if err != nil {
+		return nil, err
+	}
+
 	// Setup select prompt.
-	prompt := promptui.Select{
-		Label:             fmt.Sprintf("Select %s. / to search", kind),
+	return &promptui.Select{
+		Label:             strings.ReplaceAll(label, "%s", kind),
+		Templates:         templates,
 		Items:             data,
-		Size:              viper.GetInt("promptSize"),
+		Size:              promptSize(len(data)),
 		Searcher:          searcher,
-		StartInSearchMode: false,
+		StartInSearchMode: viper.GetBool("prompt.startInSearch"),
 		HideHelp:          true,
 		HideSelected:      false,
+		Stdout:            nopCloser{promptOutput()},
+	}, nil
+}
+
+// liveSelect prompts user to select from items received from updates. The
+// prompt is torn down and restarted with the new items whenever they change.
+// It returns the selection, or an empty string with the error received from
+// errc when updates stop first.
+func liveSelect(updates <-chan []string, errc <-chan error, input *promptInput,
+	pick func([]string, io.ReadCloser) (string, error)) (string, error) {
+	var items []string
+	select {
+	case items = <-updates:
+	case err := <-errc:
+		return "", err
 	}
 
-	// Prompt user to select item from list.
-	_, i, err := prompt.Run()
+	type selection struct {
+		item string
+		err  error
+	}
+	for {
+		done := make(chan struct{})
+		result := make(chan selection, 1)
+		go func(items []string) {
+			item, err := pick(items, input.reader(done))
+			result <- selection{item, err}
+		}(items)
+
+		select {
+		case r := <-result:
+			close(done)
+			return r.item, r.err
+		case items = <-updates:
+			close(done)
+			// Keep selection made just before items changed.
+			if r := <-result; r.err == nil {
+				return r.item, nil
+			}
+		case err := <-errc:
+			close(done)
+			if r := <-result; r.err == nil {
+				return r.item, nil
+			}
+			return "", err
+		}
+	}
+}
+
+// promptInput forwards input to prompts that are torn down and restarted so
+// that input isn't lost to a reader of a prompt that's already torn down.
+type promptInput struct {
+	data    chan []byte
+	mu      sync.Mutex
+	pending []byte
+}
+
+// newPromptInput returns promptInput forwarding input read from r.
+func newPromptInput(r io.Reader) *promptInput {
+	p := &promptInput{data: make(chan []byte)}
+	go func() {
+		defer close(p.data)
+		for {
+			buf := make([]byte, 256)
+			n, err := r.Read(buf)
+			if n > 0 {
+				p.data <- buf[:n]
+			}
+			if err != nil {
+				return
+			}
+		}
+	}()
+	return p
+}
+
+// reader returns a reader of input for a prompt that returns io.EOF once
+// done is closed so that the prompt is torn down.
+func (p *promptInput) reader(done <-chan struct{}) io.ReadCloser {
+	return inputReader{p, done}
+}
+
+// inputReader reads input of a prompt until done is closed.
+type inputReader struct {
+	input *promptInput
+	done  <-chan struct{}
+}
+
+// Read reads input left over from the previous read first.
+func (r inputReader) Read(b []byte) (int, error) {
+	select {
+	case <-r.done:
+		return 0, io.EOF
+	default:
+	}
+
+	r.input.mu.Lock()
+	if len(r.input.pending) > 0 {
+		n := copy(b, r.input.pending)
+		r.input.pending = r.input.pending[n:]
+		r.input.mu.Unlock()
+		return n, nil
+	}
+	r.input.mu.Unlock()
+
+	select {
+	case <-r.done:
+		return 0, io.EOF
+	case data, ok := <-r.input.data:
+		if !ok {
+			return 0, io.EOF
+		}
+		n := copy(b, data)
+		r.input.mu.Lock()
+		r.input.pending = append(r.input.pending, data[n:]...)
+		r.input.mu.Unlock()
+		return n, nil
+	}
+}
+
+// Close does nothing since input is shared with later prompts.
+func (inputReader) Close() error {
+	return nil
+}
+
+// confirmPrompt asks user to confirm action described by label. It returns
+// promptui.ErrAbort if user doesn't confirm.
+func confirmPrompt(label string) error {
+	prompt := promptui.Prompt{
+		Label:     label,
+		IsConfirm: true,
+		Stdout:    nopCloser{promptOutput()},
+	}
+	_, err := prompt.Run()
+	return err
+}
+
+// selectOptions prompts user to select items of data one at a time until
+// doneOption is selected and returns selected items in order of selection.
+func selectOptions(kind string, data []string) ([]string, error) {
+	var selected []string
+	remaining := append([]string{}, data...)
+
+	for len(remaining) > 0 {
+		items := remaining
+		if len(selected) > 0 {
+			items = append([]string{doneOption}, remaining...)
+		}
+
+		item, err := selectOption(fmt.Sprintf("%s (%d selected)", kind, len(selected)), items)
+		if err != nil {
+			return nil, err
+		}
+		if item == doneOption {
+			break
+		}
+
+		selected = append(selected, item)
+		remaining = removeItem(remaining, item)
+	}
+
+	return selected, nil
+}
+
+// removeItem returns data without item.
+func removeItem(data []string, item string) []string {
+	result := []string{}
+	for _, d := range data {
+		if d != item {
+			result = append(result, d)
+		}
+	}
+	return result
+}
+
+// readArg returns args with a name read from the first line of r appended
+// if args is empty. It's used to pass a name with `--stdin` in pipelines.
+func readArg(r io.Reader, args []string) ([]string, error) {
+	if len(args) > 0 {
+		return args, nil
+	}
+
+	line, err := bufio.NewReader(r).ReadString('\n')
+	if err != nil && err != io.EOF {
+		return nil, err
+	}
+	if line = strings.TrimSpace(line); line == "" {
+		return nil, fmt.Errorf("no name read from stdin")
+	}
+
+	return []string{line}, nil
+}
+
+// promptSize returns `promptSize` clamped to at least 1 since promptui
+// shows an empty window for smaller sizes. It's capped to items so that
+// short lists aren't padded with blank rows.
+func promptSize(items int) int {
+	size := viper.GetInt("promptSize")
+	if size < 1 {
+		warn("invalid prompt size %d, using 1", size)
+		size = 1
+	}
+	if items > 0 && items < size {
+		return items
+	}
+	return size
+}
+
+// inputOption prompts user to enter name of kind.
+func inputOption(kind string) (string, error) {
+	prompt := promptui.Prompt{
+		Label: fmt.Sprintf("Enter %s", kind),
+		Validate: func(input string) error {
+			if strings.TrimSpace(input) == "" {
+				return fmt.Errorf("%s is required", kind)
+			}
+			return nil
+		},
+		Stdout: nopCloser{promptOutput()},
+	}
+
+	input, err := prompt.Run()
 	if err != nil {
 		return "", err
 	}
 
-	return i, nil
+	return strings.TrimSpace(input), nil
 }
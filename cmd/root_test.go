@@ -16,33 +16,51 @@ limitations under the License.
 package cmd
 
 import (
+	"bytes"
+	"encoding/json"
 	"fmt"
+	"io"
 	"io/ioutil"
 	"os"
+	"path/filepath"
+	"reflect"
 	"strings"
 	"testing"
+	"time"
 
+	"github.com/manifoldco/promptui"
+	"github.com/spf13/cobra"
 	"github.com/spf13/viper"
+	"github.com/ckt114/kubeswitch/kubeswitch"
 )
 
 var pf = rootCmd.PersistentFlags()
 
 func execOutput() (error, string) {
-	rescueStdout := os.Stdout
+	err, out, _ := execOutputs()
+	return err, out
+}
+
+// execOutputs executes rootCmd and returns its stdout and stderr.
+func execOutputs() (error, string, string) {
+	rescueStdout, rescueStderr := os.Stdout, os.Stderr
 	r, w, _ := os.Pipe()
-	os.Stdout = w
+	er, ew, _ := os.Pipe()
+	os.Stdout, os.Stderr = w, ew
 
 	err := rootCmd.Execute()
 
 	w.Close()
+	ew.Close()
 	out, _ := ioutil.ReadAll(r)
-	os.Stdout = rescueStdout
+	errOut, _ := ioutil.ReadAll(er)
+	os.Stdout, os.Stderr = rescueStdout, rescueStderr
 
-	return err, string(out)
+	return err, string(out), string(errOut)
 }
 
 func TestConfigFlag(t *testing.T) {
-	var out string
+	var out, errOut string
 	var vs string
 
 	// Test default Kubeswitch config.
@@ -54,11 +72,51 @@ func TestConfigFlag(t *testing.T) {
 
 	// Test non-existence Kubeswitch config.
 	pf.Set("config", "/path/to/not/exists/config")
-	_, out = execOutput()
+	_, out, errOut = execOutputs()
 	warn := fmt.Sprintf("WARN: Config file \"%s\" not exists\n", viper.ConfigFileUsed())
-	if !strings.Contains(out, warn) {
+	if !strings.Contains(errOut, warn) {
 		t.Errorf("Non-existence config should throw warning")
 	}
+	if strings.Contains(out, "WARN:") {
+		t.Errorf("Expected warning not to be printed to stdout, got %s", out)
+	}
+}
+
+func TestCompletionOutput(t *testing.T) {
+	os.Unsetenv(kubeswitch.EnvVarActive)
+	os.Setenv(kubeswitch.EnvVarConfig, "../fixtures/config.yaml")
+	defer os.Unsetenv(kubeswitch.EnvVarConfig)
+	pf.Set("config", "/path/to/not/exists/config")
+	pf.Set("kubeconfig", "")
+	rootCmd.SetArgs([]string{"context", "--no-prompt"})
+	defer rootCmd.SetArgs(nil)
+
+	// Test completion output only contains contexts despite warnings.
+	_, out, errOut := execOutputs()
+	if out != "default\n" {
+		t.Errorf("Expected output to be %q, got %q", "default\n", out)
+	}
+	if !strings.Contains(errOut, "WARN:") {
+		t.Errorf("Expected warning to be printed to stderr, got %q", errOut)
+	}
+}
+
+func TestQuietFlag(t *testing.T) {
+	pf.Set("config", "/path/to/not/exists/config")
+	defer pf.Set("quiet", "false")
+
+	// Test warnings are printed to stderr without quiet set.
+	_, _, errOut := execOutputs()
+	if !strings.Contains(errOut, "WARN:") {
+		t.Errorf("Expected warning to be printed to stderr, got %q", errOut)
+	}
+
+	// Test warnings are not printed with quiet set.
+	pf.Set("quiet", "true")
+	_, out, errOut := execOutputs()
+	if strings.Contains(errOut, "WARN:") || strings.Contains(out, "WARN:") {
+		t.Errorf("Expected no warnings with quiet set, got %q, %q", out, errOut)
+	}
 }
 
 func TestNoConfigFlag(t *testing.T) {
@@ -109,3 +167,877 @@ func TestNoPromptFlag(t *testing.T) {
 		t.Errorf("Expected noPrompt to be true, got %t", vb)
 	}
 }
+
+func TestRemoveDuplicates(t *testing.T) {
+	in := []string{"", "b", "a", "b", "", "c", "a"}
+	expected := []string{"b", "a", "c"}
+
+	// Test duplicates and empty strings are removed and order is kept.
+	out := removeDuplicates(in)
+	if !reflect.DeepEqual(out, expected) {
+		t.Errorf("Expected result to be %v, got %v", expected, out)
+	}
+}
+
+func TestSetupKubeEnvVar(t *testing.T) {
+	os.Unsetenv(kubeswitch.EnvVarActive)
+	os.Unsetenv(kubeswitch.EnvVarConfig)
+	viper.Set("kubeConfig", "")
+	viper.Set("configs", []string{"../fixtures/config.yaml", "../fixtures/missing.yaml", "../fixtures/*.json"})
+	defer viper.Set("configs", nil)
+
+	// Test missing paths are excluded from KUBECONFIG.
+	if err := setupKubeEnvVar(); err != nil {
+		t.Errorf("Expected error to be %v, got %v", nil, err)
+	}
+	expected := "../fixtures/config.yaml:../fixtures/config.json"
+	if v := os.Getenv(kubeswitch.EnvVarConfig); v != expected {
+		t.Errorf("Expected KUBECONFIG to be %s, got %s", expected, v)
+	}
+
+	// Test missing paths are kept in assembled config files.
+	os.Setenv(kubeswitch.EnvVarConfig, "../fixtures/missing.yaml")
+	defer os.Unsetenv(kubeswitch.EnvVarConfig)
+	if err := setupKubeEnvVar(); err != nil {
+		t.Errorf("Expected error to be %v, got %v", nil, err)
+	}
+	files := []string{"../fixtures/missing.yaml", "../fixtures/config.yaml", "../fixtures/config.json"}
+	if !reflect.DeepEqual(configFiles, files) {
+		t.Errorf("Expected config files to be %v, got %v", files, configFiles)
+	}
+}
+
+func TestRelativeConfigs(t *testing.T) {
+	os.Unsetenv(kubeswitch.EnvVarConfig)
+	viper.Set("kubeConfig", "")
+	viper.Set("configs", []string{"*.json"})
+	viper.SetConfigFile("../fixtures/kubeswitch.yaml")
+	defer viper.Set("configs", nil)
+	defer viper.Set("relativeConfigs", nil)
+
+	// Test relative patterns are resolved from working folder by default.
+	viper.Set("relativeConfigs", false)
+	if configs, _ := kubeConfigCandidates(); len(configs) != 0 {
+		t.Errorf("Expected no configs, got %v", configs)
+	}
+
+	// Test relative patterns are resolved from Kubeswitch config's folder.
+	viper.Set("relativeConfigs", true)
+	expected := []string{"../fixtures/config.json"}
+	if configs, _ := kubeConfigCandidates(); !reflect.DeepEqual(configs, expected) {
+		t.Errorf("Expected configs to be %v, got %v", expected, configs)
+	}
+}
+
+func TestSortContexts(t *testing.T) {
+	now := time.Now()
+	infos := []kubeswitch.ContextInfo{
+		{Name: "a", Cluster: "east", LastUsed: now.Add(-time.Hour)},
+		{Name: "b", Cluster: "west"},
+		{Name: "c", Cluster: "east", LastUsed: now},
+		{Name: "d", Cluster: "central"},
+	}
+	ctxs := []string{"d", "c", "b", "a"}
+
+	// Test each sort order.
+	for by, expected := range map[string][]string{
+		"name":    {"a", "b", "c", "d"},
+		"cluster": {"d", "a", "c", "b"},
+		"recent":  {"c", "a", "b", "d"},
+	} {
+		if result, err := sortContexts(ctxs, infos, by); err != nil || !reflect.DeepEqual(result, expected) {
+			t.Errorf("Expected %s order to be %v, got %v, %v", by, expected, result, err)
+		}
+	}
+
+	// Test aliases are sorted with their context.
+	viper.Set("aliases", map[string]string{"x": "c"})
+	defer viper.Set("aliases", nil)
+	expected := []string{"c", "x", "a", "b", "d"}
+	if result, _ := sortContexts(append(ctxs, "x"), infos, "recent"); !reflect.DeepEqual(result, expected) {
+		t.Errorf("Expected recent order to be %v, got %v", expected, result)
+	}
+
+	// Test invalid sort order returns error.
+	if _, err := sortContexts(ctxs, infos, "size"); err == nil {
+		t.Errorf("Expected error for invalid sort order, got %v", err)
+	}
+}
+
+func TestParseSize(t *testing.T) {
+	// Test sizes with and without units.
+	for size, expected := range map[string]int64{"100": 100, "10B": 10, "2kb": 2048, "50MB": 50 << 20, "1 GB": 1 << 30} {
+		if n, err := parseSize(size); err != nil || n != expected {
+			t.Errorf("Expected size of %s to be %v, got %v, %v", size, expected, n, err)
+		}
+	}
+
+	// Test invalid sizes return error.
+	for _, size := range []string{"", "MB", "50TB", "-1MB", "1.5MB", "9999999999GB"} {
+		if _, err := parseSize(size); err == nil {
+			t.Errorf("Expected error for size %s, got %v", size, err)
+		}
+	}
+}
+
+func TestCopyToClipboard(t *testing.T) {
+	defer func(c [][]string) { clipboardCmds = c }(clipboardCmds)
+	file := filepath.Join(t.TempDir(), "clipboard")
+
+	// Test text is copied with the first installed tool.
+	clipboardCmds = [][]string{{"kubeswitch-missing-tool"}, {"sh", "-c", "cat > " + file}}
+	if err := copyToClipboard("/tmp/config"); err != nil {
+		t.Errorf("Expected error to be %v, got %v", nil, err)
+	}
+	if data, _ := ioutil.ReadFile(file); string(data) != "/tmp/config" {
+		t.Errorf("Expected clipboard to be %v, got %v", "/tmp/config", string(data))
+	}
+
+	// Test error is returned when no tool is installed.
+	clipboardCmds = [][]string{{"kubeswitch-missing-tool"}}
+	if err := copyToClipboard("/tmp/config"); err != errNoClipboard {
+		t.Errorf("Expected error to be %v, got %v", errNoClipboard, err)
+	}
+}
+
+func TestNeedsConfirm(t *testing.T) {
+	viper.Set("confirm", []string{"^prod-", "live$"})
+	defer viper.Set("confirm", nil)
+
+	// Test contexts matching patterns need confirmation.
+	for ctx, expected := range map[string]bool{"prod-east": true, "eu-live": true, "staging": false} {
+		if confirm, err := needsConfirm(ctx); err != nil || confirm != expected {
+			t.Errorf("Expected confirm of %s to be %v, got %v, %v", ctx, expected, confirm, err)
+		}
+	}
+
+	// Test invalid pattern returns error.
+	viper.Set("confirm", []string{"prod("})
+	if _, err := needsConfirm("prod"); err == nil {
+		t.Errorf("Expected error for invalid pattern, got %v", err)
+	}
+}
+
+func TestLogEvent(t *testing.T) {
+	var buf bytes.Buffer
+	defer func(w io.Writer) { logOutput = w }(logOutput)
+	logOutput = &buf
+	viper.Set("verbose", true)
+	defer viper.Set("verbose", nil)
+	defer viper.Set("logFormat", nil)
+
+	// Test message is printed as plain text by default.
+	logEvent("write_config", map[string]interface{}{"path": "config"}, "Wrote config %s", "config")
+	if buf.String() != "Wrote config config\n" {
+		t.Errorf("Expected output to be %q, got %q", "Wrote config config\n", buf.String())
+	}
+
+	// Test message is printed as JSON line with event and fields.
+	buf.Reset()
+	viper.Set("logFormat", "json")
+	logEvent("write_config", map[string]interface{}{"path": "config", "duration_ms": 5}, "Wrote config %s", "config")
+	var entry map[string]interface{}
+	if err := json.Unmarshal(buf.Bytes(), &entry); err != nil {
+		t.Fatalf("Expected error to be %v, got %v", nil, err)
+	}
+	if entry["event"] != "write_config" || entry["path"] != "config" || entry["duration_ms"] != float64(5) || entry["message"] != "Wrote config config" {
+		t.Errorf("Expected entry with event and fields, got %v", entry)
+	}
+
+	// Test nothing is printed unless verbose.
+	buf.Reset()
+	viper.Set("verbose", false)
+	verbose("hidden")
+	if buf.Len() != 0 {
+		t.Errorf("Expected no output, got %q", buf.String())
+	}
+}
+
+func TestProfileConfigs(t *testing.T) {
+	viper.Set("configs", []string{"default.yaml"})
+	viper.Set("profiles", map[string]interface{}{
+		"work": map[string]interface{}{"configs": []string{"work.yaml"}},
+	})
+	defer viper.Set("configs", nil)
+	defer viper.Set("profiles", nil)
+	defer viper.Set("profile", nil)
+
+	// Test top-level configs are used without profile.
+	expected := []string{"default.yaml"}
+	if configs, err := profileConfigs(); err != nil || !reflect.DeepEqual(configs, expected) {
+		t.Errorf("Expected configs to be %v, got %v, %v", expected, configs, err)
+	}
+
+	// Test configs of profile are used.
+	viper.Set("profile", "work")
+	expected = []string{"work.yaml"}
+	if configs, err := profileConfigs(); err != nil || !reflect.DeepEqual(configs, expected) {
+		t.Errorf("Expected configs to be %v, got %v, %v", expected, configs, err)
+	}
+
+	// Test undefined profile returns error.
+	viper.Set("profile", "personal")
+	if _, err := profileConfigs(); err == nil {
+		t.Errorf("Expected error for undefined profile, got %v", err)
+	}
+}
+
+func TestConfigDir(t *testing.T) {
+	origConfig := os.Getenv(kubeswitch.EnvVarConfig)
+	defer os.Setenv(kubeswitch.EnvVarConfig, origConfig)
+	viper.Set("kubeConfig", "")
+	viper.Set("configs", []string{"../fixtures"})
+	defer viper.Set("configs", nil)
+
+	// Test folders in KUBECONFIG and configs are expanded to config files inside them.
+	os.Setenv(kubeswitch.EnvVarConfig, "../fixtures")
+	expected := []string{"../fixtures/config.json", "../fixtures/config.yaml", "../fixtures/malformed.yaml"}
+	if configs, err := kubeConfigCandidates(); err != nil || !reflect.DeepEqual(configs, expected) {
+		t.Errorf("Expected configs to be %v, got %v, %v", expected, configs, err)
+	}
+
+	// Test files are kept as is.
+	if files := expandDir("../fixtures/config.yaml"); !reflect.DeepEqual(files, []string{"../fixtures/config.yaml"}) {
+		t.Errorf("Expected files to be %v, got %v", []string{"../fixtures/config.yaml"}, files)
+	}
+}
+
+func TestGlob(t *testing.T) {
+	dir := t.TempDir()
+	for _, f := range []string{"config", "aws/config", "aws/east/config", "aws/east/other", "gcp/prod/config.yaml"} {
+		path := filepath.Join(dir, filepath.FromSlash(f))
+		os.MkdirAll(filepath.Dir(path), 0700)
+		ioutil.WriteFile(path, nil, 0600)
+	}
+	join := func(files ...string) []string {
+		var paths []string
+		for _, f := range files {
+			paths = append(paths, filepath.Join(dir, filepath.FromSlash(f)))
+		}
+		return paths
+	}
+
+	// Test `**` matches zero or more nested folders.
+	for pattern, expected := range map[string][]string{
+		"**/config":      join("aws/config", "aws/east/config", "config"),
+		"aws/**/config":  join("aws/config", "aws/east/config"),
+		"*/**/*.yaml":    join("gcp/prod/config.yaml"),
+		"aws/**":         join("aws/config", "aws/east/config", "aws/east/other"),
+		"missing/**/cfg": nil,
+	} {
+		if files, err := glob(filepath.Join(dir, pattern)); err != nil || !reflect.DeepEqual(files, expected) {
+			t.Errorf("Expected files of %s to be %v, got %v, %v", pattern, expected, files, err)
+		}
+	}
+
+	// Test plain patterns behave like filepath.Glob.
+	expected, _ := filepath.Glob(filepath.Join(dir, "*"))
+	if files, _ := glob(filepath.Join(dir, "*")); !reflect.DeepEqual(files, expected) {
+		t.Errorf("Expected files to be %v, got %v", expected, files)
+	}
+}
+
+func TestFilterItems(t *testing.T) {
+	data := []string{"prod-east", "prod-west", "staging"}
+
+	// Test empty pattern returns all items.
+	if out, _ := filterItems(data, ""); !reflect.DeepEqual(out, data) {
+		t.Errorf("Expected result to be %v, got %v", data, out)
+	}
+
+	// Test pattern returns matching items.
+	expected := []string{"prod-east", "prod-west"}
+	if out, _ := filterItems(data, "prod.*"); !reflect.DeepEqual(out, expected) {
+		t.Errorf("Expected result to be %v, got %v", expected, out)
+	}
+
+	// Test invalid pattern returns error.
+	if _, err := filterItems(data, "prod("); err == nil {
+		t.Errorf("Expected error for invalid pattern, got %v", err)
+	}
+}
+
+func TestSelectTemplates(t *testing.T) {
+	defer viper.Set("prompt.template", nil)
+
+	// Test unset templates use promptui defaults.
+	viper.Set("prompt.template", nil)
+	if tpls, err := selectTemplates(); tpls != nil || err != nil {
+		t.Errorf("Expected templates to be %v, got %v, %v", nil, tpls, err)
+	}
+
+	// Test valid templates.
+	viper.Set("prompt.template", map[string]string{"active": "> {{ . | cyan }}"})
+	if tpls, err := selectTemplates(); err != nil || tpls.Active != "> {{ . | cyan }}" {
+		t.Errorf("Expected active template to be set, got %v, %v", tpls, err)
+	}
+
+	// Test invalid templates.
+	viper.Set("prompt.template", map[string]string{"active": "{{ .Name "})
+	if _, err := selectTemplates(); err == nil {
+		t.Errorf("Expected error for invalid template, got %v", err)
+	}
+}
+
+func TestResolveName(t *testing.T) {
+	names := []string{"prod", "prod-east", "prod-west", "staging-east", "dev"}
+	defer viper.Set("exact", false)
+
+	tests := []struct {
+		name     string
+		expected string
+		err      bool
+	}{
+		{"prod", "prod", false},         // exact match
+		{"stag", "staging-east", false}, // unique prefix
+		{"prod-", "", true},             // ambiguous prefix
+		{"west", "prod-west", false},    // unique substring
+		{"east", "", true},              // ambiguous substring
+		{"missing", "missing", false},   // no match
+	}
+
+	for _, tt := range tests {
+		out, err := resolveName("context", tt.name, names)
+		if (err != nil) != tt.err || out != tt.expected {
+			t.Errorf("Expected %s to resolve to %q (error %t), got %q, %v", tt.name, tt.expected, tt.err, out, err)
+		}
+	}
+
+	// Test partial names are not resolved with exact set.
+	viper.Set("exact", true)
+	if out, _ := resolveName("context", "stag", names); out != "stag" {
+		t.Errorf("Expected stag to resolve to %q, got %q", "stag", out)
+	}
+}
+
+func TestRedactConfig(t *testing.T) {
+	data, _ := ioutil.ReadFile("../fixtures/config.yaml")
+
+	// Test password of user is redacted.
+	out, err := redactConfig(data)
+	if err != nil {
+		t.Fatalf("Expected error to be %v, got %v", nil, err)
+	}
+	if strings.Contains(string(out), "f976df38c1bc4ed617413adef8844217") || !strings.Contains(string(out), redacted) {
+		t.Errorf("Expected password to be redacted, got %s", out)
+	}
+	if !strings.Contains(string(out), "username: admin") {
+		t.Errorf("Expected username to be kept, got %s", out)
+	}
+}
+
+func TestRedact(t *testing.T) {
+	data := map[string]interface{}{
+		"promptsize": 10,
+		"user": map[string]interface{}{
+			"token":           "abc",
+			"client-key-data": "def",
+		},
+		"users": []interface{}{
+			map[string]interface{}{"password": "ghi", "username": "admin"},
+		},
+	}
+	expected := map[string]interface{}{
+		"promptsize": 10,
+		"user": map[string]interface{}{
+			"token":           redacted,
+			"client-key-data": redacted,
+		},
+		"users": []interface{}{
+			map[string]interface{}{"password": redacted, "username": "admin"},
+		},
+	}
+
+	// Test sensitive values are masked at any depth.
+	if out := redact(data); !reflect.DeepEqual(out, expected) {
+		t.Errorf("Expected result to be %v, got %v", expected, out)
+	}
+
+	// Test original data is not modified.
+	if data["user"].(map[string]interface{})["token"] != "abc" {
+		t.Errorf("Expected original data to be unchanged")
+	}
+}
+
+func TestKubeconfigPath(t *testing.T) {
+	origConfig := os.Getenv(kubeswitch.EnvVarConfig)
+	defer os.Setenv(kubeswitch.EnvVarConfig, origConfig)
+
+	// Test KUBECONFIG is used when set.
+	os.Setenv(kubeswitch.EnvVarConfig, "/tmp/config")
+	if path, err := kubeconfigPath(); path != "/tmp/config" || err != nil {
+		t.Errorf("Expected path to be %s, got %s, %v", "/tmp/config", path, err)
+	}
+}
+
+func TestKubeConfigsPrecedence(t *testing.T) {
+	os.Unsetenv(kubeswitch.EnvVarConfig)
+	viper.Set("kubeConfig", "../fixtures/config.json")
+	viper.Set("configs", []string{"../fixtures/config.yaml"})
+	defer viper.Set("kubeConfig", "")
+	defer viper.Set("configs", nil)
+	defer viper.Set("precedence", nil)
+
+	// Test default precedence.
+	expected := []string{"../fixtures/config.json", "../fixtures/config.yaml"}
+	if configs, err := kubeConfigCandidates(); !reflect.DeepEqual(configs, expected) || err != nil {
+		t.Errorf("Expected configs to be %v, got %v, %v", expected, configs, err)
+	}
+
+	// Test configs taking precedence.
+	viper.Set("precedence", []string{"configs", "kubeconfig", "env"})
+	expected = []string{"../fixtures/config.yaml", "../fixtures/config.json"}
+	if configs, err := kubeConfigCandidates(); !reflect.DeepEqual(configs, expected) || err != nil {
+		t.Errorf("Expected configs to be %v, got %v, %v", expected, configs, err)
+	}
+
+	// Test invalid precedence.
+	viper.Set("precedence", []string{"invalid"})
+	if _, err := kubeConfigCandidates(); err == nil {
+		t.Errorf("Expected error for invalid precedence, got %v", err)
+	}
+}
+
+func TestKubeConfigExclusive(t *testing.T) {
+	os.Setenv(kubeswitch.EnvVarConfig, "../fixtures/config.yaml")
+	defer os.Unsetenv(kubeswitch.EnvVarConfig)
+	viper.Set("configs", []string{"../fixtures/config.yaml"})
+	viper.Set("kubeConfigExclusive", true)
+	defer viper.Set("kubeConfig", "")
+	defer viper.Set("configs", nil)
+	defer viper.Set("kubeConfigExclusive", false)
+
+	// Test kubeconfig is required.
+	viper.Set("kubeConfig", "")
+	if _, err := kubeConfigCandidates(); err == nil {
+		t.Errorf("Expected error for missing kubeconfig, got %v", err)
+	}
+
+	// Test KUBECONFIG and configs are ignored.
+	viper.Set("kubeConfig", "../fixtures/config.json")
+	expected := []string{"../fixtures/config.json"}
+	if configs, err := kubeConfigCandidates(); !reflect.DeepEqual(configs, expected) || err != nil {
+		t.Errorf("Expected configs to be %v, got %v, %v", expected, configs, err)
+	}
+}
+
+func TestPromptString(t *testing.T) {
+	// Test context and namespace are joined.
+	if out := promptString("kind", "dev", false); out != "kind:dev" {
+		t.Errorf("Expected prompt to be %s, got %s", "kind:dev", out)
+	}
+
+	// Test empty namespace uses default namespace.
+	if out := promptString("kind", "", false); out != "kind:default" {
+		t.Errorf("Expected prompt to be %s, got %s", "kind:default", out)
+	}
+
+	// Test colored prompt.
+	expected := promptColor + "kind" + promptReset + ":" + promptNsColor + "dev" + promptReset
+	if out := promptString("kind", "dev", true); out != expected {
+		t.Errorf("Expected prompt to be %q, got %q", expected, out)
+	}
+}
+
+func TestNewStatus(t *testing.T) {
+	os.Setenv(kubeswitch.EnvVarActive, "TRUE")
+	os.Setenv(kubeswitch.EnvVarConfig, "../fixtures/config.yaml")
+	defer os.Unsetenv(kubeswitch.EnvVarActive)
+	defer os.Unsetenv(kubeswitch.EnvVarConfig)
+
+	ks, err := kubeswitch.New()
+	if err != nil {
+		t.Fatalf("Expected error to be %v, got %v", nil, err)
+	}
+
+	// Test status of active session.
+	expected := status{Active: true, Kubeconfig: "../fixtures/config.yaml", Context: "default"}
+	if st := newStatus(ks); st != expected {
+		t.Errorf("Expected status to be %+v, got %+v", expected, st)
+	}
+}
+
+func TestExitCode(t *testing.T) {
+	tests := []struct {
+		err      interface{}
+		expected int
+	}{
+		{"failed", exitError},
+		{fmt.Errorf("failed"), exitError},
+		{promptui.ErrInterrupt, exitCancelled},
+		{fmt.Errorf("%w, prod", kubeswitch.ErrInvalidContext), exitUsage},
+		{fmt.Errorf("%w, dev", kubeswitch.ErrInvalidNamespace), exitUsage},
+		{kubeswitch.ErrNoKubeconfig, exitConfig},
+		{fmt.Errorf("%w /tmp/config", kubeswitch.ErrMalformedConfig), exitConfig},
+		{&kubeswitch.ShellExitError{Code: 42}, 42},
+		{&kubeswitch.ShellExitError{Code: -1}, exitError},
+	}
+
+	for _, tt := range tests {
+		if code := exitCode(tt.err); code != tt.expected {
+			t.Errorf("Expected exit code of %v to be %d, got %d", tt.err, tt.expected, code)
+		}
+	}
+}
+
+func TestSetupPlugin(t *testing.T) {
+	defer delete(rootCmd.Annotations, cobra.CommandDisplayNameAnnotation)
+
+	// Test command name is unchanged when not invoked as plugin.
+	setupPlugin("/usr/local/bin/kubeswitch")
+	if name := rootCmd.CommandPath(); name != "kubeswitch" {
+		t.Errorf("Expected name to be %s, got %s", "kubeswitch", name)
+	}
+
+	// Test command is named after kubectl plugin.
+	setupPlugin("/usr/local/bin/kubectl-switch")
+	if name := rootCmd.CommandPath(); name != "kubectl switch" {
+		t.Errorf("Expected name to be %s, got %s", "kubectl switch", name)
+	}
+}
+
+func TestAliases(t *testing.T) {
+	viper.Set("aliases", map[string]interface{}{
+		"staging": "arn:aws:eks:us-east-1:123456789012:cluster/staging",
+		"gone":    "arn:aws:eks:us-east-1:123456789012:cluster/gone",
+	})
+	defer viper.Set("aliases", nil)
+	ctxs := []string{"arn:aws:eks:us-east-1:123456789012:cluster/staging", "kind"}
+
+	// Test aliases of existing contexts are listed.
+	expected := []string{"arn:aws:eks:us-east-1:123456789012:cluster/staging", "kind", "staging"}
+	if out := withAliases(ctxs); !reflect.DeepEqual(out, expected) {
+		t.Errorf("Expected contexts to be %v, got %v", expected, out)
+	}
+
+	// Test aliases resolve to their context case-insensitively.
+	if c := resolveAlias("Staging"); c != ctxs[0] {
+		t.Errorf("Expected alias to resolve to %s, got %s", ctxs[0], c)
+	}
+
+	// Test names that aren't aliases are unchanged.
+	if c := resolveAlias("kind"); c != "kind" {
+		t.Errorf("Expected name to resolve to %s, got %s", "kind", c)
+	}
+}
+
+func TestFavorites(t *testing.T) {
+	cfg := filepath.Join(t.TempDir(), "kubeswitch.yaml")
+	ioutil.WriteFile(cfg, []byte("promptSize: 5\n"), 0600)
+	viper.SetConfigFile(cfg)
+	viper.Set("noConfig", false)
+	defer viper.Set("favorites", nil)
+
+	// Test pinned contexts are saved to config file.
+	if err := setFavorite("prod", true); err != nil {
+		t.Errorf("Expected error to be %v, got %v", nil, err)
+	}
+	if err := setFavorite("dev", true); err != nil {
+		t.Errorf("Expected error to be %v, got %v", nil, err)
+	}
+	data, _ := ioutil.ReadFile(cfg)
+	if !strings.Contains(string(data), "promptsize: 5") || !strings.Contains(string(data), "- prod") {
+		t.Errorf("Expected config file to keep settings and add favorites, got %s", data)
+	}
+
+	// Test favorites are listed first with marker.
+	expected := []string{favoriteMarker + "dev", favoriteMarker + "prod", "kind"}
+	if out := withFavorites([]string{"dev", "kind", "prod"}); !reflect.DeepEqual(out, expected) {
+		t.Errorf("Expected contexts to be %v, got %v", expected, out)
+	}
+
+	// Test unpinned context is removed from favorites.
+	if err := setFavorite("prod", false); err != nil {
+		t.Errorf("Expected error to be %v, got %v", nil, err)
+	}
+	expected = []string{"dev"}
+	if f := viper.GetStringSlice("favorites"); !reflect.DeepEqual(f, expected) {
+		t.Errorf("Expected favorites to be %v, got %v", expected, f)
+	}
+}
+
+func TestPrintContextTable(t *testing.T) {
+	infos := []kubeswitch.ContextInfo{
+		{Name: "dev", Cluster: "dev-cluster", Namespace: "app"},
+		{Name: "kind", Cluster: "kind", Current: true},
+	}
+
+	// Test columns are aligned and current context is marked.
+	var out bytes.Buffer
+	if err := printContextTable(&out, infos, "", false); err != nil {
+		t.Errorf("Expected error to be %v, got %v", nil, err)
+	}
+	expected := "CURRENT  NAME  CLUSTER      NAMESPACE\n" +
+		"         dev   dev-cluster  app\n" +
+		"*        kind  kind         \n"
+	if out.String() != expected {
+		t.Errorf("Expected table to be %q, got %q", expected, out.String())
+	}
+
+	// Test current context is colored.
+	out.Reset()
+	printContextTable(&out, infos, "kind", true)
+	if !strings.HasPrefix(out.String(), "CURRENT") || !strings.Contains(out.String(), promptColor+"*") {
+		t.Errorf("Expected colored current context, got %q", out.String())
+	}
+	if strings.Contains(out.String(), "dev") {
+		t.Errorf("Expected filtered table without %v, got %q", "dev", out.String())
+	}
+}
+
+func TestNoPrompt(t *testing.T) {
+	// Test files and pipes are not terminals.
+	f, _ := ioutil.TempFile(t.TempDir(), "out")
+	defer f.Close()
+	if isTerminal(f) {
+		t.Errorf("Expected terminal to be %v for file, got %v", false, true)
+	}
+	r, w, _ := os.Pipe()
+	defer r.Close()
+	defer w.Close()
+	if isTerminal(w) {
+		t.Errorf("Expected terminal to be %v for pipe, got %v", false, true)
+	}
+
+	origInteractive := interactive
+	defer func() { interactive = origInteractive }()
+	viper.Set("noPrompt", false)
+	defer viper.Set("noPrompt", nil)
+
+	// Test prompt is used on a terminal.
+	interactive = func() bool { return true }
+	if noPrompt() {
+		t.Errorf("Expected no prompt to be %v on terminal, got %v", false, true)
+	}
+
+	// Test prompt is disabled without a terminal.
+	interactive = func() bool { return isTerminal(w) }
+	if !noPrompt() {
+		t.Errorf("Expected no prompt to be %v without terminal, got %v", true, false)
+	}
+}
+
+func TestPromptOutput(t *testing.T) {
+	defer viper.Set("printExport", nil)
+
+	// Test prompt is drawn on stdout.
+	viper.Set("printExport", false)
+	if out := promptOutput(); out != os.Stdout {
+		t.Errorf("Expected prompt output to be %v, got %v", os.Stdout.Name(), out.Name())
+	}
+
+	// Test prompt is drawn on stderr when exports are printed for eval.
+	viper.Set("printExport", true)
+	if out := promptOutput(); out != os.Stderr {
+		t.Errorf("Expected prompt output to be %v, got %v", os.Stderr.Name(), out.Name())
+	}
+}
+
+func TestBookmarks(t *testing.T) {
+	cfg := filepath.Join(t.TempDir(), "kubeswitch.yaml")
+	viper.SetConfigFile(cfg)
+	viper.Set("noConfig", false)
+	defer viper.Set("bookmarks", nil)
+
+	// Test bookmarks are saved to config file with lowercase labels.
+	if err := addBookmark("EKS", "arn:aws:eks:us-east-1:123456789012:cluster/prod/app"); err != nil {
+		t.Errorf("Expected error to be %v, got %v", nil, err)
+	}
+	addBookmark("kind", "kind/default")
+	expected := map[string]string{"eks": "arn:aws:eks:us-east-1:123456789012:cluster/prod/app", "kind": "kind/default"}
+	if b := viper.GetStringMapString("bookmarks"); !reflect.DeepEqual(b, expected) {
+		t.Errorf("Expected bookmarks to be %v, got %v", expected, b)
+	}
+	if data, _ := ioutil.ReadFile(cfg); !strings.Contains(string(data), "kind/default") {
+		t.Errorf("Expected bookmark in config file, got %s", data)
+	}
+
+	// Test bookmark is split at last slash.
+	ctx, ns, err := parseBookmark(expected["eks"])
+	if ctx != "arn:aws:eks:us-east-1:123456789012:cluster/prod" || ns != "app" || err != nil {
+		t.Errorf("Expected context and namespace, got %v, %v, %v", ctx, ns, err)
+	}
+
+	// Test invalid bookmarks.
+	for _, b := range []string{"kind", "kind/", "/default"} {
+		if _, _, err := parseBookmark(b); err == nil {
+			t.Errorf("Expected error for bookmark %s, got %v", b, err)
+		}
+	}
+}
+
+func TestWriteOutput(t *testing.T) {
+	os.Setenv(kubeswitch.EnvVarConfig, "../fixtures/config.yaml")
+	ks, _ := kubeswitch.New()
+
+	// Test config is written to file.
+	path := filepath.Join(t.TempDir(), "config")
+	if err := writeOutput(ks, path); err != nil {
+		t.Errorf("Expected error to be %v, got %v", nil, err)
+	}
+	data, _ := ioutil.ReadFile(path)
+
+	// Test config is written to stdout without output.
+	origStdout := os.Stdout
+	r, w, _ := os.Pipe()
+	os.Stdout = w
+	err := writeOutput(ks, "")
+	w.Close()
+	os.Stdout = origStdout
+	out, _ := ioutil.ReadAll(r)
+	if err != nil || string(out) != string(data) {
+		t.Errorf("Expected stdout to be %s, got %s, %v", data, out, err)
+	}
+}
+
+func TestPromptSize(t *testing.T) {
+	defer viper.Set("promptSize", nil)
+
+	// Test valid size is used as is.
+	viper.Set("promptSize", 10)
+	if size := promptSize(20); size != 10 {
+		t.Errorf("Expected size to be %v, got %v", 10, size)
+	}
+
+	// Test size is capped to length of short lists.
+	if size := promptSize(3); size != 3 {
+		t.Errorf("Expected size to be %v, got %v", 3, size)
+	}
+
+	// Test zero and negative sizes are clamped.
+	for _, s := range []int{0, -5} {
+		viper.Set("promptSize", s)
+		if size := promptSize(20); size != 1 {
+			t.Errorf("Expected size to be %v for %v, got %v", 1, s, size)
+		}
+	}
+}
+
+func TestReadArg(t *testing.T) {
+	// Test name is read from first line and trimmed.
+	expected := []string{"prod"}
+	if args, err := readArg(strings.NewReader("  prod \nstaging\n"), nil); err != nil || !reflect.DeepEqual(args, expected) {
+		t.Errorf("Expected args to be %v, got %v, %v", expected, args, err)
+	}
+
+	// Test passed args are kept.
+	expected = []string{"dev"}
+	if args, err := readArg(strings.NewReader("prod"), expected); err != nil || !reflect.DeepEqual(args, expected) {
+		t.Errorf("Expected args to be %v, got %v, %v", expected, args, err)
+	}
+
+	// Test empty input returns error.
+	if _, err := readArg(strings.NewReader(" \n"), nil); err == nil {
+		t.Errorf("Expected error for empty input, got %v", err)
+	}
+}
+
+func TestResolveNamespace(t *testing.T) {
+	os.Setenv(kubeswitch.EnvVarConfig, "../fixtures/config.yaml")
+	defer os.Unsetenv(kubeswitch.EnvVarConfig)
+	ks, _ := kubeswitch.New()
+	ks.AddNamespace("default")
+	ks.AddNamespace("kube-system")
+
+	origInteractive, origConfirmCreate := interactive, confirmCreate
+	defer func() { interactive, confirmCreate = origInteractive, origConfirmCreate }()
+	interactive = func() bool { return true }
+	viper.Set("noPrompt", false)
+	defer viper.Set("noPrompt", nil)
+	var asked []string
+	confirm := true
+	confirmCreate = func(ns string) bool {
+		asked = append(asked, ns)
+		return confirm
+	}
+
+	tests := []struct {
+		name     string
+		create   bool
+		confirm  bool
+		expected string
+		created  bool
+		asked    bool
+	}{
+		{"kube-sys", false, true, "kube-system", false, false}, // unique partial name resolves without asking
+		{"dev", false, true, "dev", true, true},                // no match is created after confirming
+		{"dev", false, false, "dev", false, true},              // no match isn't created when declined
+		{"kube-sys", true, false, "kube-sys", true, false},     // --create creates name as is
+		{"default", true, false, "default", false, false},      // --create sets existing namespace
+	}
+
+	for _, tt := range tests {
+		asked, confirm = nil, tt.confirm
+		n, created, err := resolveNamespace(ks, tt.name, tt.create)
+		if err != nil || n != tt.expected || created != tt.created {
+			t.Errorf("Expected %s to resolve to %s, %v, got %s, %v, %v", tt.name, tt.expected, tt.created, n, created, err)
+		}
+		if (len(asked) > 0) != tt.asked {
+			t.Errorf("Expected asking to create %s to be %v, got %v", tt.name, tt.asked, asked)
+		}
+	}
+
+	// Test nothing is created without prompt.
+	interactive = func() bool { return false }
+	if n, created, _ := resolveNamespace(ks, "dev", false); created {
+		t.Errorf("Expected %s not to be created without prompt", n)
+	}
+}
+
+func TestLiveSelect(t *testing.T) {
+	r, w, _ := os.Pipe()
+	defer r.Close()
+	defer w.Close()
+	input := newPromptInput(r)
+
+	picks := make(chan []string, 2)
+	pick := func(items []string, stdin io.ReadCloser) (string, error) {
+		picks <- items
+		b := make([]byte, 10)
+		n, err := stdin.Read(b)
+		if err != nil {
+			return "", promptui.ErrEOF
+		}
+		return string(b[:n]), nil
+	}
+
+	updates := make(chan []string, 1)
+	errc := make(chan error, 1)
+	type selection struct {
+		item string
+		err  error
+	}
+	result := make(chan selection)
+	updates <- []string{"a"}
+	go func() {
+		item, err := liveSelect(updates, errc, input, pick)
+		result <- selection{item, err}
+	}()
+
+	// Test prompt is restarted with changed items.
+	if items := <-picks; !reflect.DeepEqual(items, []string{"a"}) {
+		t.Errorf("Expected items to be %v, got %v", []string{"a"}, items)
+	}
+	updates <- []string{"a", "b"}
+	if items := <-picks; !reflect.DeepEqual(items, []string{"a", "b"}) {
+		t.Errorf("Expected items to be %v, got %v", []string{"a", "b"}, items)
+	}
+
+	// Test input goes to the restarted prompt.
+	w.Write([]byte("b"))
+	if r := <-result; r.item != "b" || r.err != nil {
+		t.Errorf("Expected selection to be %v, got %v, %v", "b", r.item, r.err)
+	}
+
+	// Test prompt is torn down when watch stops.
+	updates <- []string{"a"}
+	go func() {
+		item, err := liveSelect(updates, errc, input, pick)
+		result <- selection{item, err}
+	}()
+	<-picks
+	errc <- nil
+	if r := <-result; r.item != "" || r.err != nil {
+		t.Errorf("Expected no selection, got %v, %v", r.item, r.err)
+	}
+}
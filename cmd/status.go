@@ -0,0 +1,89 @@
+/*
+Copyright © 2020 Chung Tran <chung.k.tran@gmail.com>
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+
+	"github.com/spf13/cobra"
+	"github.com/ckt114/kubeswitch/kubeswitch"
+)
+
+// status holds state of current Kubeswitch session.
+type status struct {
+	// Active is true if inside Kubeswitch session.
+	Active bool `json:"active"`
+
+	// Kubeconfig is the value of KUBECONFIG.
+	Kubeconfig string `json:"kubeconfig"`
+
+	// Context is the name of current context.
+	Context string `json:"context"`
+
+	// Namespace is the default namespace of current context.
+	Namespace string `json:"namespace"`
+}
+
+// statusCmd represents the status command that prints state
+// of current Kubeswitch session.
+var statusCmd = &cobra.Command{
+	Use:   "status",
+	Short: "Print session status",
+	Args:  cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		ks := newKubeswitch()
+		st := newStatus(ks)
+
+		// Copy KUBECONFIG for pasting into another terminal.
+		clip, _ := cmd.Flags().GetBool("copy-to-clipboard")
+		copyOutput(clip, st.Kubeconfig)
+
+		// Print status as JSON for tools.
+		if asJSON, _ := cmd.Flags().GetBool("json"); asJSON {
+			data, err := json.MarshalIndent(st, "", "  ")
+			if err != nil {
+				fail(err)
+			}
+			fmt.Println(string(data))
+			return
+		}
+
+		fmt.Println("Active:", st.Active)
+		fmt.Println("KUBECONFIG:", st.Kubeconfig)
+		fmt.Println("Context:", st.Context)
+		fmt.Println("Namespace:", st.Namespace)
+	},
+}
+
+// newStatus returns status of current session with context and namespace from ks.
+func newStatus(ks *kubeswitch.Kubeswitch) status {
+	return status{
+		Active:     kubeswitch.IsActive(),
+		Kubeconfig: os.Getenv(kubeswitch.EnvVarConfig),
+		Context:    ks.CurrentContext(),
+		Namespace:  ks.CurrentNamespace(),
+	}
+}
+
+func init() {
+	rootCmd.AddCommand(statusCmd)
+
+	// Local flags only available to this command.
+	statusCmd.Flags().Bool("json", false, "print status as JSON")
+	statusCmd.Flags().Bool("copy-to-clipboard", false, "copy KUBECONFIG path to clipboard")
+}
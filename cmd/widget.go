@@ -0,0 +1,63 @@
+/*
+Copyright © 2020 Chung Tran <chung.k.tran@gmail.com>
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/spf13/cobra"
+)
+
+const (
+	// bashWidget binds Ctrl-S to pick a context in the current Bash shell.
+	// Flow control must be disabled for Ctrl-S to reach the shell.
+	bashWidget = `__kubeswitch_widget() {
+  eval "$(kubeswitch context --print-export </dev/tty)"
+}
+stty -ixon 2>/dev/null
+bind -x '"\C-s": __kubeswitch_widget'
+`
+
+	// zshWidget binds Ctrl-S to pick a context in the current ZSH shell.
+	zshWidget = `__kubeswitch_widget() {
+  eval "$(kubeswitch context --print-export </dev/tty)"
+  zle reset-prompt
+}
+zle -N __kubeswitch_widget
+bindkey '^S' __kubeswitch_widget
+`
+)
+
+// widgetCmd represents the widget command that prints a shell script binding
+// a hotkey to the context picker. The picker updates the current shell with
+// `--print-export` instead of running a nested shell.
+var widgetCmd = &cobra.Command{
+	Use:       "widget SHELL",
+	Short:     "Print shell widget binding Ctrl-S to context picker",
+	Args:      cobra.MatchAll(cobra.ExactArgs(1), cobra.OnlyValidArgs),
+	ValidArgs: []string{"bash", "zsh"},
+	Run: func(cmd *cobra.Command, args []string) {
+		if args[0] == "zsh" {
+			fmt.Print(zshWidget)
+		} else {
+			fmt.Print(bashWidget)
+		}
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(widgetCmd)
+}
@@ -0,0 +1,66 @@
+/*
+Copyright © 2020 Chung Tran <chung.k.tran@gmail.com>
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package kubeswitch
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+
+	"k8s.io/client-go/tools/clientcmd"
+)
+
+const (
+	// defaultEditor is the editor used when VISUAL and EDITOR are unset.
+	defaultEditor = "vi"
+)
+
+// Edit opens the config file at path in the user's editor from VISUAL or
+// EDITOR env vars and waits for it to exit. The file is validated after
+// editing so that mistakes are reported right away.
+func Edit(path string) error {
+	editor := os.Getenv("VISUAL")
+	if editor == "" {
+		editor = os.Getenv("EDITOR")
+	}
+	if editor == "" {
+		editor = defaultEditor
+	}
+
+	// Editor can have arguments such as `code --wait`.
+	args := append(strings.Fields(editor), path)
+	if err := runAttached(exec.Command(args[0], args[1:]...)); err != nil {
+		return fmt.Errorf("editor %s failed: %w", editor, err)
+	}
+
+	return Validate(path)
+}
+
+// Validate returns an error if the config file at path can't be parsed
+// or references clusters and users that don't exist.
+func Validate(path string) error {
+	config, err := loadFile(path)
+	if err != nil {
+		return err
+	}
+
+	if err := clientcmd.Validate(*config); err != nil {
+		return fmt.Errorf("invalid config %s: %w", path, err)
+	}
+
+	return nil
+}
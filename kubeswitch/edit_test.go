@@ -0,0 +1,67 @@
+/*
+Copyright © 2020 Chung Tran <chung.k.tran@gmail.com>
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package kubeswitch
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestEdit(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "config")
+	data, _ := ioutil.ReadFile("../fixtures/config.yaml")
+	ioutil.WriteFile(path, data, 0600)
+
+	origVisual, origEditor := os.Getenv("VISUAL"), os.Getenv("EDITOR")
+	defer os.Setenv("VISUAL", origVisual)
+	defer os.Setenv("EDITOR", origEditor)
+	os.Unsetenv("VISUAL")
+
+	// Test valid config after editing.
+	os.Setenv("EDITOR", "true")
+	if err := Edit(path); err != nil {
+		t.Errorf("Expected error to be %v, got %v", nil, err)
+	}
+
+	// Test editor breaking config reports parse error.
+	editor := filepath.Join(dir, "editor")
+	ioutil.WriteFile(editor, []byte("#!/bin/sh\necho 'clusters: [' > \"$1\"\n"), 0755)
+	os.Setenv("EDITOR", editor)
+	if err := Edit(path); err == nil {
+		t.Errorf("Expected error for invalid config, got %v", err)
+	}
+
+	// Test failing editor.
+	os.Setenv("VISUAL", "false")
+	if err := Edit(path); err == nil {
+		t.Errorf("Expected error for failing editor, got %v", err)
+	}
+}
+
+func TestValidate(t *testing.T) {
+	// Test valid config.
+	if err := Validate("../fixtures/config.yaml"); err != nil {
+		t.Errorf("Expected error to be %v, got %v", nil, err)
+	}
+
+	// Test missing config.
+	if err := Validate("/path/to/not/exists/config"); err == nil {
+		t.Errorf("Expected error for missing config, got %v", err)
+	}
+}
@@ -0,0 +1,90 @@
+/*
+Copyright © 2020 Chung Tran <chung.k.tran@gmail.com>
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package kubeswitch
+
+import (
+	"fmt"
+
+	api "k8s.io/client-go/tools/clientcmd/api"
+)
+
+// Export returns an instance of Kubeswitch with a config containing only
+// context ctx and the cluster and user it references. Current context is
+// exported when ctx is empty. Set noCreds to strip credentials of the user.
+func (k *Kubeswitch) Export(ctx string, noCreds bool) (*Kubeswitch, error) {
+	if ctx == "" {
+		ctx = k.config.CurrentContext
+	}
+	if !k.IsValidContext(ctx) {
+		return nil, fmt.Errorf("%w, %s", ErrInvalidContext, ctx)
+	}
+	c := k.config.Contexts[ctx]
+
+	config := api.NewConfig()
+	config.Contexts[ctx] = c
+	config.CurrentContext = ctx
+	if cluster, ok := k.config.Clusters[c.Cluster]; ok {
+		config.Clusters[c.Cluster] = cluster
+	}
+	if user, ok := k.config.AuthInfos[c.AuthInfo]; ok {
+		if noCreds {
+			user = api.NewAuthInfo()
+		}
+		config.AuthInfos[c.AuthInfo] = user
+	}
+
+	return &Kubeswitch{config: config}, nil
+}
+
+// View returns the loaded config serialized as YAML. Only current context and
+// the cluster and user it references are included if minify is set.
+func (k *Kubeswitch) View(minify bool) ([]byte, error) {
+	if minify {
+		exported, err := k.Export("", false)
+		if err != nil {
+			return nil, err
+		}
+		return exported.Bytes()
+	}
+
+	return k.Bytes()
+}
+
+// Minify removes clusters and users that are not referenced by any context
+// from the loaded config.
+func (k *Kubeswitch) Minify() error {
+	clusters := map[string]bool{}
+	users := map[string]bool{}
+	for _, c := range k.config.Contexts {
+		clusters[c.Cluster] = true
+		users[c.AuthInfo] = true
+	}
+
+	for name := range k.config.Clusters {
+		if !clusters[name] {
+			Logf("Removing unused cluster %s", name)
+			delete(k.config.Clusters, name)
+		}
+	}
+	for name := range k.config.AuthInfos {
+		if !users[name] {
+			Logf("Removing unused user %s", name)
+			delete(k.config.AuthInfos, name)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,104 @@
+/*
+Copyright © 2020 Chung Tran <chung.k.tran@gmail.com>
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package kubeswitch
+
+import (
+	"errors"
+	"testing"
+
+	"k8s.io/client-go/tools/clientcmd"
+	api "k8s.io/client-go/tools/clientcmd/api"
+)
+
+func TestExport(t *testing.T) {
+	k := &Kubeswitch{config: api.NewConfig()}
+	k.config.Clusters["east"] = &api.Cluster{Server: "https://east"}
+	k.config.Clusters["west"] = &api.Cluster{Server: "https://west"}
+	k.config.AuthInfos["admin"] = &api.AuthInfo{Token: "secret"}
+	k.config.AuthInfos["viewer"] = &api.AuthInfo{Token: "secret"}
+	k.config.Contexts["east"] = &api.Context{Cluster: "east", AuthInfo: "admin"}
+	k.config.Contexts["west"] = &api.Context{Cluster: "west", AuthInfo: "viewer"}
+	k.config.CurrentContext = "east"
+
+	// Test current context is exported with its cluster and user.
+	e, err := k.Export("", false)
+	if err != nil {
+		t.Fatalf("Expected error to be %v, got %v", nil, err)
+	}
+	if len(e.config.Contexts) != 1 || len(e.config.Clusters) != 1 || len(e.config.AuthInfos) != 1 {
+		t.Errorf("Expected only one context, cluster, and user, got %v", e.config)
+	}
+	if e.config.Clusters["east"] == nil || e.config.AuthInfos["admin"].Token != "secret" {
+		t.Errorf("Expected cluster %s and user %s to be exported, got %v", "east", "admin", e.config)
+	}
+
+	// Test credentials are stripped.
+	e, err = k.Export("west", true)
+	if err != nil {
+		t.Fatalf("Expected error to be %v, got %v", nil, err)
+	}
+	if e.config.CurrentContext != "west" || e.config.AuthInfos["viewer"].Token != "" {
+		t.Errorf("Expected context %s without credentials, got %v", "west", e.config)
+	}
+
+	// Test invalid context returns error.
+	if _, err := k.Export("missing", false); !errors.Is(err, ErrInvalidContext) {
+		t.Errorf("Expected error to be %v, got %v", ErrInvalidContext, err)
+	}
+}
+
+func TestMinify(t *testing.T) {
+	k := &Kubeswitch{config: api.NewConfig()}
+	k.config.Clusters["east"] = &api.Cluster{Server: "https://east"}
+	k.config.Clusters["orphan"] = &api.Cluster{Server: "https://orphan"}
+	k.config.AuthInfos["admin"] = &api.AuthInfo{Token: "secret"}
+	k.config.AuthInfos["orphan"] = &api.AuthInfo{Token: "secret"}
+	k.config.Contexts["east"] = &api.Context{Cluster: "east", AuthInfo: "admin"}
+
+	if err := k.Minify(); err != nil {
+		t.Fatalf("Expected error to be %v, got %v", nil, err)
+	}
+
+	// Test referenced entries are kept.
+	if k.config.Clusters["east"] == nil || k.config.AuthInfos["admin"] == nil {
+		t.Errorf("Expected referenced cluster and user to be kept, got %v", k.config)
+	}
+
+	// Test orphaned entries are removed.
+	if k.config.Clusters["orphan"] != nil || k.config.AuthInfos["orphan"] != nil {
+		t.Errorf("Expected orphaned cluster and user to be removed, got %v", k.config)
+	}
+}
+
+func TestView(t *testing.T) {
+	k, _ := New()
+	k.config.Clusters["unused"] = api.NewCluster()
+
+	// Test full config is viewed.
+	data, err := k.View(false)
+	if err != nil {
+		t.Fatalf("Expected error to be %v, got %v", nil, err)
+	}
+	if config, _ := clientcmd.Load(data); len(config.Clusters) != len(k.config.Clusters) {
+		t.Errorf("Expected %d clusters, got %d", len(k.config.Clusters), len(config.Clusters))
+	}
+
+	// Test minified config only has current context's cluster.
+	data, _ = k.View(true)
+	if config, _ := clientcmd.Load(data); len(config.Clusters) != 1 || config.Clusters["unused"] != nil {
+		t.Errorf("Expected only cluster of current context, got %v", config.Clusters)
+	}
+}
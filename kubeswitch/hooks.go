@@ -0,0 +1,65 @@
+/*
+Copyright © 2020 Chung Tran <chung.k.tran@gmail.com>
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package kubeswitch
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+)
+
+// Hooks holds shell commands run around switching context or namespace.
+// Commands are run by defaultShell with EnvVarContext and EnvVarNamespace
+// set to the context and namespace switched to.
+type Hooks struct {
+	// PreContext runs before switching context. Switching is aborted
+	// if it fails.
+	PreContext string
+
+	// PostContext runs after switching context.
+	PostContext string
+
+	// PostNamespace runs after switching namespace.
+	PostNamespace string
+}
+
+// runHooks runs hook commands in order for current context and namespace.
+func (k *Kubeswitch) runHooks(hooks []string) error {
+	for _, hook := range hooks {
+		if err := runHook(hook, k.config.CurrentContext, k.CurrentNamespace()); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// runHook runs hook command with ctx and ns set in env vars. Output is written
+// to stderr so it's not mixed with env var exports printed to stdout.
+func runHook(hook, ctx, ns string) error {
+	if hook == "" {
+		return nil
+	}
+
+	LogEventf("run_hook", map[string]interface{}{"hook": hook, "context": ctx}, "Running hook %s", hook)
+	cmd := exec.Command(defaultShell, "-c", hook)
+	cmd.Env = append(os.Environ(), EnvVarContext+"="+ctx, EnvVarNamespace+"="+ns)
+	cmd.Stdout = os.Stderr
+	if err := runAttached(cmd); err != nil {
+		return fmt.Errorf("hook `%s` failed: %w", hook, err)
+	}
+
+	return nil
+}
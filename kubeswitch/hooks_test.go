@@ -0,0 +1,77 @@
+/*
+Copyright © 2020 Chung Tran <chung.k.tran@gmail.com>
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package kubeswitch
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestRunHook(t *testing.T) {
+	out := filepath.Join(t.TempDir(), "out")
+
+	// Test hook is run with context and namespace env vars.
+	if err := runHook("echo $KUBESWITCH_CONTEXT/$KUBESWITCH_NAMESPACE > "+out, "kind", "dev"); err != nil {
+		t.Errorf("Expected error to be %v, got %v", nil, err)
+	}
+	if data, _ := ioutil.ReadFile(out); string(data) != "kind/dev\n" {
+		t.Errorf("Expected hook output to be %q, got %q", "kind/dev\n", data)
+	}
+
+	// Test failing hook returns error.
+	if err := runHook("exit 1", "kind", "dev"); err == nil {
+		t.Errorf("Expected error for failing hook, got %v", err)
+	}
+
+	// Test empty hook is skipped.
+	if err := runHook("", "kind", "dev"); err != nil {
+		t.Errorf("Expected error to be %v, got %v", nil, err)
+	}
+}
+
+func TestHooks(t *testing.T) {
+	dir := t.TempDir()
+	origKubeDir := kubeDir
+	kubeDir = func() (string, error) { return dir, nil }
+	defer func() { kubeDir = origKubeDir }()
+
+	k, _ := New()
+	origConfig := os.Getenv(EnvVarConfig)
+	defer os.Setenv(EnvVarConfig, origConfig)
+	defer os.Unsetenv(EnvVarActive)
+	os.Setenv(EnvVarActive, "TRUE")
+	os.Setenv(EnvVarConfig, filepath.Join(dir, "config"))
+	k.config.Contexts["other"] = k.config.Contexts["default"].DeepCopy()
+	k.config.CurrentContext = "default"
+	out := filepath.Join(dir, "out")
+
+	// Test failing pre hook aborts switching context.
+	k.Hooks = Hooks{PreContext: "exit 1"}
+	if err := k.SetContext("other"); err == nil || k.config.CurrentContext != "default" {
+		t.Errorf("Expected context to stay %v with error, got %v, %v", "default", k.config.CurrentContext, err)
+	}
+
+	// Test post hook runs after switching context.
+	k.Hooks = Hooks{PostContext: "echo $KUBESWITCH_CONTEXT > " + out}
+	if err := k.SetContext("other"); err != nil {
+		t.Errorf("Expected error to be %v, got %v", nil, err)
+	}
+	if data, _ := ioutil.ReadFile(out); string(data) != "other\n" {
+		t.Errorf("Expected hook output to be %q, got %q", "other\n", data)
+	}
+}
@@ -0,0 +1,88 @@
+/*
+Copyright © 2020 Chung Tran <chung.k.tran@gmail.com>
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package kubeswitch
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"k8s.io/client-go/tools/clientcmd"
+)
+
+var (
+	// importDir stores config files imported into kubeswitch.
+	importDir = func() (string, error) {
+		dir, err := kubeDir()
+		if err != nil {
+			return "", err
+		}
+		return dir + "/kubeswitch.d", nil
+	}
+)
+
+// Import copies the config file at path into the import folder and returns
+// the path of the copy. When name is set, the config's only context is
+// renamed to name and the copy is named after it.
+func Import(path, name string) (string, error) {
+	config, err := loadFile(path)
+	if err != nil {
+		return "", err
+	}
+
+	// Make relative paths of certificates absolute since they are resolved
+	// from the file's folder, which changes once the file is copied.
+	if err := clientcmd.ResolveLocalPaths(config); err != nil {
+		return "", err
+	}
+
+	dir, err := importDir()
+	if err != nil {
+		return "", err
+	}
+
+	dest := filepath.Join(dir, filepath.Base(path))
+	if name != "" {
+		// Name is used as file name so it must not escape the import folder.
+		if strings.ContainsAny(name, `/\`) || strings.Contains(name, "..") {
+			return "", fmt.Errorf("invalid name %s, must not contain path separators or ..", name)
+		}
+		if len(config.Contexts) != 1 {
+			return "", fmt.Errorf("cannot rename context, %s has %d contexts", path, len(config.Contexts))
+		}
+
+		// Rename the only context and keep it as current context.
+		for ctx, c := range config.Contexts {
+			delete(config.Contexts, ctx)
+			config.Contexts[name] = c
+		}
+		config.CurrentContext = name
+		dest = filepath.Join(dir, name+".yaml")
+	}
+
+	// Don't overwrite previously imported configs.
+	if _, err := os.Stat(dest); err == nil {
+		return "", fmt.Errorf("%s already exists", dest)
+	}
+
+	k := &Kubeswitch{config: config}
+	if err := k.writeConfig(dest); err != nil {
+		return "", err
+	}
+
+	return dest, nil
+}
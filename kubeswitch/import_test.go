@@ -0,0 +1,79 @@
+/*
+Copyright © 2020 Chung Tran <chung.k.tran@gmail.com>
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package kubeswitch
+
+import (
+	"path/filepath"
+	"testing"
+
+	"k8s.io/client-go/tools/clientcmd"
+)
+
+func TestImport(t *testing.T) {
+	dir := t.TempDir()
+	defer func(f func() (string, error)) { kubeDir = f }(kubeDir)
+	kubeDir = func() (string, error) { return dir, nil }
+
+	// Test config is copied into import folder.
+	expected := filepath.Join(dir, "kubeswitch.d", "config.yaml")
+	if dest, err := Import("../fixtures/config.yaml", ""); err != nil || dest != expected {
+		t.Errorf("Expected import to %s, got %s, %v", expected, dest, err)
+	}
+
+	// Test importing the same file again returns error.
+	if _, err := Import("../fixtures/config.yaml", ""); err == nil {
+		t.Errorf("Expected error for existing file, got %v", err)
+	}
+
+	// Test context is renamed to name.
+	expected = filepath.Join(dir, "kubeswitch.d", "kind.yaml")
+	dest, err := Import("../fixtures/config.json", "kind")
+	if err != nil || dest != expected {
+		t.Errorf("Expected import to %s, got %s, %v", expected, dest, err)
+	}
+	config, err := clientcmd.LoadFromFile(dest)
+	if err != nil {
+		t.Fatalf("Expected error to be %v, got %v", nil, err)
+	}
+	if _, ok := config.Contexts["kind"]; !ok || config.CurrentContext != "kind" {
+		t.Errorf("Expected context to be renamed to %s, got %v", "kind", config.Contexts)
+	}
+
+	// Test names escaping the import folder return error.
+	for _, name := range []string{"../kind", "a/b", `a\b`, ".."} {
+		if _, err := Import("../fixtures/config.json", name); err == nil {
+			t.Errorf("Expected error for name %s, got %v", name, err)
+		}
+	}
+}
+
+func TestImportRelativeCert(t *testing.T) {
+	dir := t.TempDir()
+	defer func(f func() (string, error)) { kubeDir = f }(kubeDir)
+	kubeDir = func() (string, error) { return dir, nil }
+	path, _ := relativeCertConfig(t)
+
+	// Test relative certificate path points to the source file's folder.
+	dest, err := Import(path, "")
+	if err != nil {
+		t.Fatalf("Expected error to be %v, got %v", nil, err)
+	}
+	config, _ := clientcmd.LoadFromFile(dest)
+	expected := filepath.Join(filepath.Dir(path), "ca.crt")
+	if ca := config.Clusters["default"].CertificateAuthority; ca != expected {
+		t.Errorf("Expected certificate path to be %s, got %s", expected, ca)
+	}
+}
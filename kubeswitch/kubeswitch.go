@@ -16,19 +16,30 @@ limitations under the License.
 package kubeswitch
 
 import (
+	"bytes"
 	"context"
+	"errors"
 	"fmt"
+	"hash/fnv"
 	"io/ioutil"
+	"net"
 	"os"
+	"path/filepath"
+	"regexp"
 	"sort"
+	"strconv"
 	"strings"
+	"sync"
 	"syscall"
 	"time"
 
 	homedir "github.com/mitchellh/go-homedir"
 	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/duration"
 	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/rest"
 	"k8s.io/client-go/tools/clientcmd"
 	api "k8s.io/client-go/tools/clientcmd/api"
 
@@ -43,25 +54,117 @@ const (
 	// EnvVarConfig is the env var that points to a
 	// session's kube config.
 	EnvVarConfig = "KUBECONFIG"
+
+	// EnvVarDepth is the env var that holds how many
+	// session shells are nested.
+	EnvVarDepth = "KUBESWITCH_DEPTH"
+
+	// EnvVarContext is the env var that holds a session's
+	// context for shell prompts and hooks.
+	EnvVarContext = "KUBESWITCH_CONTEXT"
+
+	// EnvVarNamespace is the env var that holds a session's
+	// namespace for shell prompts and hooks.
+	EnvVarNamespace = "KUBESWITCH_NAMESPACE"
 )
 
 var (
+	// ErrNoKubeconfig is returned when no Kubernetes config is found.
+	ErrNoKubeconfig = errors.New("no Kubernetes config found")
+
+	// ErrInvalidContext is returned when a context is not in loaded config.
+	ErrInvalidContext = errors.New("invalid context")
+
+	// ErrUnreachable is returned when the cluster of a context can't be reached.
+	ErrUnreachable = errors.New("cluster unreachable")
+
+	// ErrInvalidNamespace is returned when a namespace is not in loaded namespaces.
+	ErrInvalidNamespace = errors.New("invalid namespace")
+
+	// ErrMalformedConfig is returned when a Kubernetes config file can't
+	// be parsed.
+	ErrMalformedConfig = errors.New("malformed config")
+
+	// ErrNamespacesNotLoaded is returned when namespaces are used before
+	// they are loaded with LoadNamespaces.
+	ErrNamespacesNotLoaded = errors.New("namespaces are not loaded, call LoadNamespaces first")
+
+	// Logf prints verbose messages. It does nothing by default
+	// and can be replaced to enable verbose output.
+	Logf = func(format string, a ...interface{}) {}
+
+	// Warnf prints warning messages to stderr by default and can be
+	// replaced, e.g. to silence warnings.
+	Warnf = func(format string, a ...interface{}) {
+		fmt.Fprintf(os.Stderr, "WARN: "+format+"\n", a...)
+	}
+
+	// LogEventf prints verbose messages of events with their structured
+	// fields. It prints the message with Logf by default and can be
+	// replaced to keep the fields, e.g. for JSON logs.
+	LogEventf = func(event string, fields map[string]interface{}, format string, a ...interface{}) {
+		Logf(format, a...)
+	}
+
 	// kubeDir returns the default kube folder.
-	kubeDir = func() string {
+	kubeDir = func() (string, error) {
 		home, err := homedir.Dir()
 		if err != nil {
-			fmt.Println(err)
-			os.Exit(1)
+			return "", err
 		}
-		return home + "/.kube"
+		return home + "/.kube", nil
 	}
 
+	// unsafeChars matches characters not safe in session file names.
+	unsafeChars = regexp.MustCompile(`[^A-Za-z0-9._-]`)
+
+	// healthTimeout is the timeout of the cluster health check.
+	healthTimeout = 3 * time.Second
+
+	// waitInterval is the delay between polls of a namespace's status.
+	waitInterval = time.Second
+
 	// sessionDir stores kubeswitch copied config session files.
-	sessionDir = func() string {
-		return kubeDir() + "/tmp"
+	sessionDir = func() (string, error) {
+		dir, err := kubeDir()
+		if err != nil {
+			return "", err
+		}
+		return dir + "/tmp", nil
 	}
 )
 
+// NamespaceInfo holds details of a namespace.
+type NamespaceInfo struct {
+	// Name is the name of the namespace.
+	Name string `json:"name"`
+
+	// Status is the phase of the namespace such as Active or Terminating.
+	Status string `json:"status"`
+
+	// Age is how long ago the namespace was created.
+	Age string `json:"age"`
+}
+
+// ContextInfo holds details of a context.
+type ContextInfo struct {
+	// Name is the name of the context.
+	Name string `json:"name"`
+
+	// Cluster is the name of the context's cluster.
+	Cluster string `json:"cluster"`
+
+	// Namespace is the default namespace of the context.
+	Namespace string `json:"namespace"`
+
+	// Current is true for the current context.
+	Current bool `json:"current"`
+
+	// LastUsed is when the context was last switched to. It's zero
+	// if the context was never switched to.
+	LastUsed time.Time `json:"lastUsed"`
+}
+
 // Kubeswitch holds loaded kube config and loaded namespaces.
 type Kubeswitch struct {
 	// config contains the content of loaded config
@@ -70,26 +173,172 @@ type Kubeswitch struct {
 	// namespaces contains namespaces from Kubernetes
 	// for current context.
 	namespaces *corev1.NamespaceList
+
+	// kube is cached client for context kubeContext.
+	kube        kubernetes.Interface
+	kubeContext string
+
+	// clientFactory creates Kubernetes clients from REST config.
+	// Clients are created with kubernetes.NewForConfig when nil.
+	clientFactory func(*rest.Config) (kubernetes.Interface, error)
+
+	// PageSize is the number of namespaces fetched per request
+	// when loading namespaces. Zero fetches all in one request.
+	PageSize int64
+
+	// RetryAttempts is the number of attempts of a request when
+	// loading namespaces fails with a transient error.
+	RetryAttempts int
+
+	// RetryDelay is the delay before the first retry. The delay
+	// doubles for each following retry.
+	RetryDelay time.Duration
+
+	// Concurrency is the maximum number of contexts namespaces are
+	// fetched from at once. Zero fetches from all contexts at once.
+	Concurrency int
+
+	// As is the user to impersonate in requests to Kubernetes.
+	As string
+
+	// AsGroups are the groups to impersonate in requests to Kubernetes.
+	AsGroups []string
+
+	// Insecure skips verifying certificates of Kubernetes API servers.
+	Insecure bool
+
+	// SortRecent sorts listed namespaces by the time they were last
+	// selected for current context instead of by name.
+	SortRecent bool
+
+	// Wait polls a created namespace until it's active
+	// before setting it.
+	Wait bool
+
+	// NoRestore disables restoring the last selected namespace
+	// of a context when switching to it.
+	NoRestore bool
+
+	// PrintExport prints shell commands to export the session env vars
+	// instead of running a new shell for the session.
+	PrintExport bool
+
+	// NoShell writes the session file and prints its env vars
+	// without running a new shell for the session.
+	NoShell bool
+
+	// CleanupOnExit deletes the session file when the session's
+	// shell exits.
+	CleanupOnExit bool
+
+	// SessionByContext names session files after their context instead
+	// of a timestamp so switching to a context reuses its session file.
+	SessionByContext bool
+
+	// Shell is the shell to run for new sessions. The user's
+	// shell is detected when empty.
+	Shell string
+
+	// MaxDepth is the maximum number of nested session shells.
+	// Zero allows any depth.
+	MaxDepth int
+
+	// Hooks are shell commands run around switching.
+	Hooks Hooks
+
+	// NoSession changes context and namespace in the Kubernetes config
+	// files in place instead of a session file without running a shell.
+	NoSession bool
+}
+
+// Options holds options for loading config files.
+type Options struct {
+	// NoFlatten keeps references to certificate and key files instead
+	// of embedding their content into the loaded config.
+	NoFlatten bool
 }
 
 // New returns an instance of Kubeswitch after loading the config
 // file from passed in path, KUBECONFIG env var, or default location.
 func New() (*Kubeswitch, error) {
+	return NewWithOptions(Options{})
+}
+
+// NewWithOptions returns an instance of Kubeswitch like New
+// with config files loaded using opts.
+func NewWithOptions(opts Options) (*Kubeswitch, error) {
+	start := time.Now()
+	defer func() {
+		d := time.Since(start)
+		LogEventf("load_config", map[string]interface{}{"duration_ms": d.Milliseconds()}, "Loaded config in %s", d)
+	}()
+
 	// Load config files.
 	po := clientcmd.NewDefaultPathOptions()
 	config, err := po.GetStartingConfig()
 	if err != nil {
+		// Find the malformed file for a clearer error.
+		for _, path := range po.GetLoadingPrecedence() {
+			if _, ferr := loadFile(path); ferr != nil && !os.IsNotExist(ferr) {
+				return nil, ferr
+			}
+		}
 		return nil, err
 	}
 
-	// Flatten config files into single file.
-	if err := api.FlattenConfig(config); err != nil {
-		return nil, err
+	// Missing config files are loaded as an empty config.
+	if len(config.Contexts) == 0 && len(config.Clusters) == 0 && len(config.AuthInfos) == 0 {
+		return nil, ErrNoKubeconfig
+	}
+
+	if opts.NoFlatten {
+		// Resolve paths of referenced files relative to their config file
+		// so they stay valid when the config is written to a session file.
+		if err := clientcmd.ResolveLocalPaths(config); err != nil {
+			return nil, err
+		}
+	} else {
+		// Flatten config files into single file.
+		if err := api.FlattenConfig(config); err != nil {
+			return nil, err
+		}
 	}
 
 	return &Kubeswitch{config: config}, nil
 }
 
+// loadFile loads config file at path. Parse errors are wrapped with the path
+// and whether the file looks like JSON or YAML.
+func loadFile(path string) (*api.Config, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	config, err := clientcmd.Load(data)
+	if err != nil {
+		format := "YAML"
+		if bytes.HasPrefix(bytes.TrimSpace(data), []byte("{")) {
+			format = "JSON"
+		}
+		return nil, fmt.Errorf("%w %s, check its %s syntax: %w", ErrMalformedConfig, path, format, err)
+	}
+
+	// Record origin of entries like clientcmd.LoadFromFile so that relative
+	// paths of certificates are resolved from the file's folder.
+	for _, cluster := range config.Clusters {
+		cluster.LocationOfOrigin = path
+	}
+	for _, user := range config.AuthInfos {
+		user.LocationOfOrigin = path
+	}
+	for _, ctx := range config.Contexts {
+		ctx.LocationOfOrigin = path
+	}
+
+	return config, nil
+}
+
 // ListContexts return context names in loaded config.
 func (k *Kubeswitch) ListContexts() *[]string {
 	var ctxs []string
@@ -99,21 +348,124 @@ func (k *Kubeswitch) ListContexts() *[]string {
 	}
 
 	sort.Strings(ctxs)
+	ctxs = removeDuplicateNames(ctxs)
 	return &ctxs
 }
 
+// removeDuplicateNames returns sorted names without names that only differ
+// from another name by surrounding whitespace, preferring the trimmed name.
+// Names differing by case are kept since Kubernetes names are case-sensitive.
+func removeDuplicateNames(names []string) []string {
+	result := []string{}
+	index := map[string]int{}
+	lower := map[string]string{}
+
+	for _, name := range names {
+		key := strings.TrimSpace(name)
+		if i, ok := index[key]; ok {
+			Logf("Dropping duplicate name %q of %q", name, result[i])
+			if name == key {
+				result[i] = name
+			}
+			continue
+		}
+
+		if other, ok := lower[strings.ToLower(key)]; ok {
+			Logf("Names %q and %q only differ by case", other, key)
+		}
+		lower[strings.ToLower(key)] = key
+
+		index[key] = len(result)
+		result = append(result, name)
+	}
+
+	// Sort again since trimmed names may have replaced untrimmed ones.
+	sort.Strings(result)
+	return result
+}
+
+// ContextDetails return details of contexts in loaded config sorted by name.
+func (k *Kubeswitch) ContextDetails() []ContextInfo {
+	infos := []ContextInfo{}
+
+	// Details are still useful without times contexts were last used.
+	st, err := loadState()
+	if err != nil {
+		Logf("Unable to load state, contexts have no last used time: %s", err)
+		st = &state{}
+	}
+
+	for name, ctx := range k.config.Contexts {
+		infos = append(infos, ContextInfo{
+			Name:      name,
+			Cluster:   ctx.Cluster,
+			Namespace: ctx.Namespace,
+			Current:   name == k.config.CurrentContext,
+			LastUsed:  st.ContextsUsed[name],
+		})
+	}
+
+	sort.Slice(infos, func(i, j int) bool { return infos[i].Name < infos[j].Name })
+	return infos
+}
+
+// FindContextsByServer returns names of contexts whose cluster's server
+// URL contains server, sorted by name.
+func (k *Kubeswitch) FindContextsByServer(server string) []string {
+	ctxs := []string{}
+
+	for name, ctx := range k.config.Contexts {
+		if cluster, ok := k.config.Clusters[ctx.Cluster]; ok && strings.Contains(cluster.Server, server) {
+			ctxs = append(ctxs, name)
+		}
+	}
+
+	sort.Strings(ctxs)
+	return ctxs
+}
+
 // SetContext set context as current context.
 func (k *Kubeswitch) SetContext(ctx string) error {
+	if !k.IsValidContext(ctx) {
+		ctx = trimName("context", ctx)
+	}
+
 	// Error out if context is not valid.
 	if !k.IsValidContext(ctx) {
-		return fmt.Errorf("invalid context, %s", ctx)
+		return fmt.Errorf("%w, %s", ErrInvalidContext, ctx)
+	}
+
+	prevCtx, prevNs := k.config.CurrentContext, k.CurrentNamespace()
+
+	// Run hook before switching so it can abort the switch.
+	if ctx != prevCtx {
+		if err := runHook(k.Hooks.PreContext, ctx, k.config.Contexts[ctx].Namespace); err != nil {
+			return err
+		}
 	}
 
 	// Set current context to chosen context.
 	k.config.CurrentContext = ctx
+	// Recording context is best-effort and must not block switching.
+	if err := recordContext(ctx); err != nil {
+		Logf("Unable to record context %s: %s", ctx, err)
+	}
+
+	// Restore last selected namespace for context unless disabled.
+	if !k.NoRestore {
+		if err := k.restoreNamespace(); err != nil {
+			return err
+		}
+	}
+
+	// Skip rewriting session config or running a new shell if nothing changed.
+	if ctx == prevCtx && k.CurrentNamespace() == prevNs {
+		Logf("Context %s is already set", ctx)
+		return nil
+	}
 
 	// Create/update session config.
-	if err := k.setupSession(); err != nil {
+	if err := k.setupSession(k.Hooks.PostContext); err != nil {
 		return err
 	}
 
@@ -124,16 +476,61 @@ func (k *Kubeswitch) SetContext(ctx string) error {
 // write it to a temporary file and set KUBECONFIG to that file's path if not in
 // a Kubeswitch sessions. Otherwise, just write the changes to the path defined in
 // KUBECONFIG env var.
-func (k *Kubeswitch) setupSession() error {
+func (k *Kubeswitch) setupSession(hooks ...string) error {
+	// Change Kubernetes config files in place without a session.
+	if k.NoSession {
+		if err := k.modifyConfig(); err != nil {
+			return err
+		}
+		return k.runHooks(hooks)
+	}
+
 	// Just write the config to KUBECONFIG if in Kubeswitch session.
 	if IsActive() {
 		if err := k.writeConfig(os.Getenv(EnvVarConfig)); err != nil {
 			return err
 		}
+		if err := k.runHooks(hooks); err != nil {
+			return err
+		}
+
+		// The session shell's env vars can't be changed from here, so print
+		// exports of the switched context and namespace for user to eval.
+		if k.PrintExport {
+			shell := k.Shell
+			if shell == "" {
+				shell = resolveShell()
+			}
+			fmt.Println(exportCmd(shell, EnvVarContext, k.config.CurrentContext))
+			fmt.Println(exportCmd(shell, EnvVarNamespace, k.CurrentNamespace()))
+		}
 	} else {
-		// Construct temporary timestamped kubeconfig session file.
-		now := time.Now()
-		kubePath := fmt.Sprintf("%s/config_%d", sessionDir(), now.UnixNano())
+		// Use configured shell or detect user's shell for the session.
+		shell := k.Shell
+		if shell == "" {
+			shell = resolveShell()
+		}
+
+		// Error out before writing session file if shell can't be run.
+		depth := Depth() + 1
+		if !k.PrintExport && !k.NoShell {
+			if err := checkExecutable(shell); err != nil {
+				return err
+			}
+			if k.MaxDepth > 0 && depth > k.MaxDepth {
+				return fmt.Errorf("session depth %d exceeds max depth %d, exit some session shells first", depth, k.MaxDepth)
+			}
+		}
+
+		// Construct kubeconfig session file named by timestamp or context.
+		dir, err := createSessionDir()
+		if err != nil {
+			return err
+		}
+		kubePath := fmt.Sprintf("%s/config_%d", dir, time.Now().UnixNano())
+		if k.SessionByContext {
+			kubePath = dir + "/" + sessionName(k.config.CurrentContext)
+		}
 
 		// Write config to temp path for new session.
 		if err := k.writeConfig(kubePath); err != nil {
@@ -143,15 +540,163 @@ func (k *Kubeswitch) setupSession() error {
 		// Set env vars that will be visible when running new shell below.
 		os.Setenv(EnvVarActive, "TRUE")
 		os.Setenv(EnvVarConfig, kubePath)
+		os.Setenv(EnvVarContext, k.config.CurrentContext)
+		os.Setenv(EnvVarNamespace, k.CurrentNamespace())
 
-		// Run a shell with new config path set as env var above.
-		syscall.Exec(os.Getenv("SHELL"), []string{os.Getenv("SHELL")}, syscall.Environ())
+		// Run hooks with session file so they act on switched context.
+		if err := k.runHooks(hooks); err != nil {
+			return err
+		}
+
+		// Print env vars for user to eval in current shell instead of running a new shell.
+		if k.PrintExport {
+			fmt.Println(exportCmd(shell, EnvVarActive, "TRUE"))
+			fmt.Println(exportCmd(shell, EnvVarConfig, kubePath))
+			fmt.Println(exportCmd(shell, EnvVarContext, k.config.CurrentContext))
+			fmt.Println(exportCmd(shell, EnvVarNamespace, k.CurrentNamespace()))
+			return nil
+		}
+
+		// Print env vars for the session file without running a new shell.
+		if k.NoShell {
+			fmt.Printf("%s=%s\n", EnvVarConfig, kubePath)
+			fmt.Printf("%s=%s\n", EnvVarActive, "TRUE")
+			fmt.Printf("%s=%s\n", EnvVarContext, k.config.CurrentContext)
+			fmt.Printf("%s=%s\n", EnvVarNamespace, k.CurrentNamespace())
+			return nil
+		}
+
+		// Run a shell with new config path set as env var above
+		// and wait for the user to exit it. Its exit status is
+		// returned after the session has ended.
+		os.Setenv(EnvVarDepth, strconv.Itoa(depth))
+		Logf("Running shell %s at depth %d", shell, depth)
+		shellErr := runShell(shell)
+		var exitErr *ShellExitError
+		if shellErr != nil && !errors.As(shellErr, &exitErr) {
+			return shellErr
+		}
+
+		// Delete session file now that the session has ended.
+		if k.CleanupOnExit {
+			Logf("Removing session file %s", kubePath)
+			if err := os.Remove(kubePath); err != nil {
+				return err
+			}
+		}
+
+		return shellErr
 	}
 
 	return nil
 
 }
 
+// modifyConfig writes current context and its namespace to the Kubernetes
+// config files they are loaded from. Current context is written to the first
+// config file and namespace to the file defining the context.
+func (k *Kubeswitch) modifyConfig() error {
+	Warnf("Changing Kubernetes config files in place affects all shells using them")
+
+	// Load config files again without flattening so that only current
+	// context and namespace are changed in the files.
+	po := clientcmd.NewDefaultPathOptions()
+	config, err := po.GetStartingConfig()
+	if err != nil {
+		return err
+	}
+	config.CurrentContext = k.config.CurrentContext
+	if ctx, ok := config.Contexts[k.config.CurrentContext]; ok {
+		ctx.Namespace = k.CurrentNamespace()
+	}
+
+	return clientcmd.ModifyConfig(po, *config, true)
+}
+
+// CurrentContext returns the name of current context.
+func (k *Kubeswitch) CurrentContext() string {
+	return k.config.CurrentContext
+}
+
+// CurrentNamespace returns the default namespace of current context.
+// It's empty when the context has no default namespace.
+func (k *Kubeswitch) CurrentNamespace() string {
+	if ctx, ok := k.config.Contexts[k.config.CurrentContext]; ok {
+		return ctx.Namespace
+	}
+	return ""
+}
+
+// CurrentNamespaceExists loads namespaces live from Kubernetes and returns
+// true if namespace of current context is one of them. It's true when current
+// context has no namespace set.
+func (k *Kubeswitch) CurrentNamespaceExists() (bool, error) {
+	return k.CurrentNamespaceExistsContext(context.Background())
+}
+
+// CurrentNamespaceExistsContext is like CurrentNamespaceExists but uses ctx
+// for the API request.
+func (k *Kubeswitch) CurrentNamespaceExistsContext(ctx context.Context) (bool, error) {
+	ns := k.CurrentNamespace()
+	if ns == "" {
+		return true, nil
+	}
+
+	if err := k.LoadNamespacesContext(ctx); err != nil {
+		return false, err
+	}
+
+	return k.IsValidNamespace(ns), nil
+}
+
+// sessionName returns session file name for context ctx. Characters unsafe in
+// file names are replaced and a hash of ctx is appended when any are replaced
+// so that different contexts don't share a session file.
+func sessionName(ctx string) string {
+	safe := unsafeChars.ReplaceAllString(ctx, "_")
+	if safe != ctx {
+		h := fnv.New32a()
+		h.Write([]byte(ctx))
+		safe = fmt.Sprintf("%s_%08x", safe, h.Sum32())
+	}
+	return "config_" + safe
+}
+
+// trimName returns name of kind without surrounding whitespace, e.g. from
+// copy-pasting it. It's only used when name isn't valid as is since names
+// may contain whitespace.
+func trimName(kind, name string) string {
+	trimmed := strings.TrimSpace(name)
+	if trimmed != name {
+		Logf("Trimmed %s %q to %q", kind, name, trimmed)
+	}
+	return trimmed
+}
+
+// recordContext records in state that context ctx was switched to.
+func recordContext(ctx string) error {
+	return updateState(func(st *state) { st.setContext(ctx) })
+}
+
+// restoreNamespace sets the current context's namespace to the last selected
+// namespace for that context if it doesn't already have a namespace.
+func (k *Kubeswitch) restoreNamespace() error {
+	ctx, ok := k.config.Contexts[k.config.CurrentContext]
+	if !ok || ctx.Namespace != "" {
+		return nil
+	}
+
+	// Restoring namespace is best-effort and must not block switching.
+	st, err := loadState()
+	if err != nil {
+		Logf("Unable to load state, namespace is not restored: %s", err)
+		return nil
+	}
+	ctx.Namespace = st.Namespaces[k.config.CurrentContext]
+
+	return nil
+}
+
 // IsValidContext return true if context is one of the contexts.
 func (k *Kubeswitch) IsValidContext(ctx string) bool {
 	for _, c := range *k.ListContexts() {
@@ -164,52 +709,421 @@ func (k *Kubeswitch) IsValidContext(ctx string) bool {
 
 // LoadNamespaces loads list of namespaces for current context live from Kubernetes.
 func (k *Kubeswitch) LoadNamespaces() error {
-	// Convert config into []bytes.
-	cfgBytes, err := clientcmd.Write(*k.config)
+	return k.LoadNamespacesContext(context.Background())
+}
+
+// LoadNamespacesContext loads list of namespaces for current context live from
+// Kubernetes using ctx for the API request. Callers can use ctx to set deadlines
+// or cancel the request.
+func (k *Kubeswitch) LoadNamespacesContext(ctx context.Context) error {
+	kube, err := k.client()
 	if err != nil {
 		return err
 	}
 
-	// Create REST config from config []bytes.
-	restCfg, err := clientcmd.RESTConfigFromKubeConfig(cfgBytes)
+	start := time.Now()
+	nsList, err := k.fetchNamespaces(ctx, kube, k.config.CurrentContext)
+	d := time.Since(start)
+	LogEventf("list_namespaces", map[string]interface{}{"context": k.config.CurrentContext, "duration_ms": d.Milliseconds()},
+		"Listed namespaces of context %s in %s", k.config.CurrentContext, d)
 	if err != nil {
 		return err
 	}
+	k.namespaces = nsList
 
-	// Create kube REST client from REST config.
-	kube, err := kubernetes.NewForConfig(restCfg)
+	return nil
+}
+
+// LoadNamespacesForContexts returns namespace names of each context in ctxs
+// live from Kubernetes. Namespaces are fetched from up to Concurrency contexts
+// at once.
+func (k *Kubeswitch) LoadNamespacesForContexts(ctxs []string) (map[string][]string, error) {
+	return k.LoadNamespacesForContextsContext(context.Background(), ctxs)
+}
+
+// LoadNamespacesForContextsContext is like LoadNamespacesForContexts but uses
+// ctx for the API requests. Namespaces of contexts that are fetched are returned
+// along with errors of contexts that failed.
+func (k *Kubeswitch) LoadNamespacesForContextsContext(ctx context.Context, ctxs []string) (map[string][]string, error) {
+	var (
+		mu     sync.Mutex
+		wg     sync.WaitGroup
+		result = map[string][]string{}
+		errs   []error
+	)
+
+	workers := k.Concurrency
+	if workers <= 0 || workers > len(ctxs) {
+		workers = len(ctxs)
+	}
+
+	// Start workers that fetch namespaces of contexts from names.
+	names := make(chan string)
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for name := range names {
+				nss, err := k.namespacesForContext(ctx, name)
+
+				mu.Lock()
+				if err != nil {
+					errs = append(errs, err)
+				} else {
+					result[name] = nss
+				}
+				mu.Unlock()
+			}
+		}()
+	}
+
+	for _, name := range ctxs {
+		names <- name
+	}
+	close(names)
+	wg.Wait()
+
+	// Failure of some contexts doesn't discard namespaces of other contexts.
+	return result, errors.Join(errs...)
+}
+
+// namespacesForContext returns namespace names of context name live from Kubernetes.
+func (k *Kubeswitch) namespacesForContext(ctx context.Context, name string) ([]string, error) {
+	if !k.IsValidContext(name) {
+		return nil, fmt.Errorf("%w, %s", ErrInvalidContext, name)
+	}
+
+	_, kube, err := k.newClient(name)
 	if err != nil {
-		return err
+		return nil, err
 	}
+	nsList, err := k.fetchNamespaces(ctx, kube, name)
+	if err != nil {
+		return nil, err
+	}
+
+	var nss []string
+	for _, ns := range nsList.Items {
+		nss = append(nss, ns.Name)
+	}
+	return nss, nil
+}
+
+// fetchNamespaces returns all namespaces of context name using kube
+// fetching one page at a time.
+func (k *Kubeswitch) fetchNamespaces(ctx context.Context, kube kubernetes.Interface, name string) (*corev1.NamespaceList, error) {
+	nsList := &corev1.NamespaceList{}
+	opts := metav1.ListOptions{Limit: k.PageSize}
+	for {
+		page, err := k.listNamespaces(ctx, kube, opts)
+		if apierrors.IsForbidden(err) && (k.As != "" || len(k.AsGroups) > 0) {
+			return nil, fmt.Errorf("no permission to list namespaces in context %s as user %q and groups %v: %w", name, k.As, k.AsGroups, err)
+		} else if apierrors.IsForbidden(err) {
+			return nil, fmt.Errorf("no permission to list namespaces in context %s: %w", name, err)
+		} else if apierrors.IsUnauthorized(err) {
+			return nil, fmt.Errorf("unauthorized in context %s, credentials may be invalid or expired: %w", name, err)
+		} else if err != nil {
+			return nil, err
+		}
+		nsList.Items = append(nsList.Items, page.Items...)
+		nsList.ResourceVersion = page.ResourceVersion
+
+		// Stop when there are no more pages to fetch.
+		if page.Continue == "" {
+			break
+		}
+		opts.Continue = page.Continue
+	}
+
+	return nsList, nil
+}
+
+// listNamespaces lists a page of namespaces and retries with exponential
+// backoff on transient errors until RetryAttempts or ctx is done.
+func (k *Kubeswitch) listNamespaces(ctx context.Context, kube kubernetes.Interface, opts metav1.ListOptions) (*corev1.NamespaceList, error) {
+	delay := k.RetryDelay
+	for attempt := 1; ; attempt++ {
+		nsList, err := kube.CoreV1().Namespaces().List(ctx, opts)
+		if err == nil || attempt >= k.RetryAttempts || !isRetryable(err) {
+			return nsList, err
+		}
+		Logf("Listing namespaces failed, retrying in %s: %v", delay, err)
+
+		// Wait before retrying unless ctx is done first.
+		select {
+		case <-ctx.Done():
+			return nil, err
+		case <-time.After(delay):
+		}
+		delay *= 2
+	}
+}
+
+// CheckHealth returns ErrUnreachable if the cluster of current context
+// doesn't respond to a health check request.
+func (k *Kubeswitch) CheckHealth() error {
+	return k.CheckHealthContext(context.Background())
+}
 
-	// Fetch list of namespaces from Kubernetes.
-	k.namespaces, err = kube.CoreV1().Namespaces().List(context.Background(), metav1.ListOptions{})
+// CheckHealthContext is like CheckHealth but uses ctx for the API request.
+func (k *Kubeswitch) CheckHealthContext(ctx context.Context) error {
+	kube, err := k.client()
 	if err != nil {
 		return err
 	}
 
+	ctx, cancel := context.WithTimeout(ctx, healthTimeout)
+	defer cancel()
+	_, err = kube.Discovery().RESTClient().Get().AbsPath("/healthz").DoRaw(ctx)
+
+	// Any response from the server, even an error status, means it's reachable.
+	var status apierrors.APIStatus
+	if err != nil && !errors.As(err, &status) {
+		return fmt.Errorf("%w, %s: %v", ErrUnreachable, k.config.CurrentContext, err)
+	}
+
 	return nil
 }
 
-// ListNamespaces return namespaces live from Kubernetes.
+// isRetryable returns true if err is transient such as a timeout,
+// server error, or refused connection.
+func isRetryable(err error) bool {
+	if apierrors.IsTimeout(err) || apierrors.IsServerTimeout(err) ||
+		apierrors.IsTooManyRequests(err) || apierrors.IsInternalError(err) ||
+		apierrors.IsServiceUnavailable(err) || apierrors.IsUnexpectedServerError(err) {
+		return true
+	}
+
+	// Retry other server errors with 5xx status codes.
+	if status, ok := err.(apierrors.APIStatus); ok && status.Status().Code >= 500 {
+		return true
+	}
+
+	// Retry network errors that are likely temporary.
+	if errors.Is(err, syscall.ECONNREFUSED) || errors.Is(err, syscall.ECONNRESET) {
+		return true
+	}
+	var netErr net.Error
+	return errors.As(err, &netErr) && netErr.Timeout()
+}
+
+// CreateNamespace creates namespace in Kubernetes for current context and sets it
+// as default namespace. An already existing namespace is set without error.
+func (k *Kubeswitch) CreateNamespace(ns string) error {
+	return k.CreateNamespaceContext(context.Background(), ns)
+}
+
+// CreateNamespaceContext is like CreateNamespace but uses ctx for the API request.
+func (k *Kubeswitch) CreateNamespaceContext(ctx context.Context, ns string) error {
+	kube, err := k.client()
+	if err != nil {
+		return err
+	}
+
+	// Create namespace in Kubernetes.
+	nsObj := corev1.Namespace{}
+	nsObj.Name = ns
+	_, err = kube.CoreV1().Namespaces().Create(ctx, &nsObj, metav1.CreateOptions{})
+	if apierrors.IsForbidden(err) {
+		return fmt.Errorf("no permission to create namespace %s in context %s: %w", ns, k.config.CurrentContext, err)
+	} else if err != nil && !apierrors.IsAlreadyExists(err) {
+		return err
+	}
+
+	// Add namespace to loaded namespaces so it's valid to set.
+	k.AddNamespace(ns)
+
+	// Wait for namespace before setting it since setting it may run a shell.
+	if k.Wait {
+		if err := k.WaitNamespaceContext(ctx, ns); err != nil {
+			return err
+		}
+	}
+
+	return k.SetNamespace(ns)
+}
+
+// WaitNamespace polls namespace ns until it's Active.
+func (k *Kubeswitch) WaitNamespace(ns string) error {
+	return k.WaitNamespaceContext(context.Background(), ns)
+}
+
+// WaitNamespaceContext is like WaitNamespace but stops polling when ctx is done.
+func (k *Kubeswitch) WaitNamespaceContext(ctx context.Context, ns string) error {
+	kube, err := k.client()
+	if err != nil {
+		return err
+	}
+
+	for {
+		nsObj, err := kube.CoreV1().Namespaces().Get(ctx, ns, metav1.GetOptions{})
+		if err == nil && nsObj.Status.Phase == corev1.NamespaceActive {
+			Logf("Namespace %s is active", ns)
+			return nil
+		} else if err != nil && !apierrors.IsNotFound(err) && !isRetryable(err) {
+			return err
+		}
+
+		if err != nil {
+			Logf("Waiting for namespace %s: %s", ns, err)
+		} else {
+			Logf("Waiting for namespace %s to be active, it's %s", ns, nsObj.Status.Phase)
+		}
+
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("namespace %s is not active: %w", ns, ctx.Err())
+		case <-time.After(waitInterval):
+		}
+	}
+}
+
+// AddNamespace adds namespace to loaded namespaces without checking it exists
+// in Kubernetes so that it can be set. Use it when namespaces can't be listed.
+func (k *Kubeswitch) AddNamespace(ns string) {
+	if k.namespaces == nil {
+		k.namespaces = &corev1.NamespaceList{}
+	}
+	if !k.IsValidNamespace(ns) {
+		nsObj := corev1.Namespace{}
+		nsObj.Name = ns
+		k.namespaces.Items = append(k.namespaces.Items, nsObj)
+	}
+}
+
+// client returns a Kubernetes client for current context. The client is
+// cached and only rebuilt when current context changes.
+func (k *Kubeswitch) client() (kubernetes.Interface, error) {
+	if k.kube != nil && k.kubeContext == k.config.CurrentContext {
+		return k.kube, nil
+	}
+
+	_, kube, err := k.newClient(k.config.CurrentContext)
+	if err != nil {
+		return nil, err
+	}
+	k.kube, k.kubeContext = kube, k.config.CurrentContext
+
+	return kube, nil
+}
+
+// newClient returns REST config and a new Kubernetes client for context name.
+func (k *Kubeswitch) newClient(name string) (*rest.Config, kubernetes.Interface, error) {
+	// Convert config for context into []bytes.
+	config := *k.config
+	config.CurrentContext = name
+	cfgBytes, err := clientcmd.Write(config)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	// Create REST config from config []bytes.
+	restCfg, err := clientcmd.RESTConfigFromKubeConfig(cfgBytes)
+	if err != nil {
+		return nil, nil, err
+	}
+	restCfg.Impersonate = rest.ImpersonationConfig{UserName: k.As, Groups: k.AsGroups}
+
+	// Certificate authority can't be set with insecure.
+	if k.Insecure {
+		restCfg.Insecure = true
+		restCfg.CAFile = ""
+		restCfg.CAData = nil
+	}
+
+	// Create kube REST client from REST config.
+	factory := k.clientFactory
+	if factory == nil {
+		factory = newClientForConfig
+	}
+	kube, err := factory(restCfg)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return restCfg, kube, nil
+}
+
+// newClientForConfig returns a Kubernetes client created from restCfg.
+func newClientForConfig(restCfg *rest.Config) (kubernetes.Interface, error) {
+	return kubernetes.NewForConfig(restCfg)
+}
+
+// ListNamespaces return namespaces live from Kubernetes sorted by name,
+// or by last selected first if SortRecent is set.
 func (k *Kubeswitch) ListNamespaces() *[]string {
 	var nss []string
 
+	if k.namespaces == nil {
+		Logf("%s", ErrNamespacesNotLoaded)
+		return &[]string{}
+	}
+
 	for _, n := range k.namespaces.Items {
 		nss = append(nss, n.Name)
 	}
 
 	sort.Strings(nss)
+	nss = removeDuplicateNames(nss)
+	if k.SortRecent {
+		k.sortRecent(nss)
+	}
 	return &nss
 }
 
+// sortRecent sorts name sorted nss by time they were last selected for
+// current context. Namespaces never selected are kept in name order.
+func (k *Kubeswitch) sortRecent(nss []string) {
+	st, err := loadState()
+	if err != nil {
+		Logf("Unable to load state, sorting namespaces by name: %s", err)
+		return
+	}
+
+	lastUsed := st.LastUsed[k.config.CurrentContext]
+	sort.SliceStable(nss, func(i, j int) bool {
+		return lastUsed[nss[i]].After(lastUsed[nss[j]])
+	})
+}
+
+// NamespaceDetails return details of namespaces live from Kubernetes sorted by name.
+func (k *Kubeswitch) NamespaceDetails() []NamespaceInfo {
+	infos := []NamespaceInfo{}
+
+	if k.namespaces == nil {
+		Logf("%s", ErrNamespacesNotLoaded)
+		return infos
+	}
+
+	for _, n := range k.namespaces.Items {
+		infos = append(infos, NamespaceInfo{
+			Name:   n.Name,
+			Status: string(n.Status.Phase),
+			Age:    duration.HumanDuration(time.Since(n.CreationTimestamp.Time)),
+		})
+	}
+
+	sort.Slice(infos, func(i, j int) bool { return infos[i].Name < infos[j].Name })
+	return infos
+}
+
 // SetNamespace sets default namespace for current context.
 func (k *Kubeswitch) SetNamespace(ns string) error {
+	if k.namespaces == nil {
+		return ErrNamespacesNotLoaded
+	}
+
+	if !k.IsValidNamespace(ns) {
+		ns = trimName("namespace", ns)
+	}
+
 	// Error out if namespace is not valid.
 	if !k.IsValidNamespace(ns) {
-		return fmt.Errorf("invalid namespace, %s", ns)
+		return fmt.Errorf("%w, %s", ErrInvalidNamespace, ns)
 	}
 
+	prevNs := k.CurrentNamespace()
+
 	// Find the current context and set its default namespace.
 	for name, ctx := range k.config.Contexts {
 		if name == k.config.CurrentContext {
@@ -217,8 +1131,88 @@ func (k *Kubeswitch) SetNamespace(ns string) error {
 		}
 	}
 
+	// Remember namespace so it can be restored when switching back to context.
+	cur := k.config.CurrentContext
+	if err := updateState(func(st *state) { st.setNamespace(cur, ns) }); err != nil {
+		Logf("Unable to remember namespace %s: %s", ns, err)
+	}
+
+	// Skip rewriting session config or running a new shell if nothing changed.
+	if ns == prevNs {
+		Logf("Namespace %s is already set", ns)
+		return nil
+	}
+
 	// Create/update session config.
-	if err := k.setupSession(); err != nil {
+	if err := k.setupSession(k.Hooks.PostNamespace); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// SetContextNamespace sets current context and its default namespace
+// together so that only one session is set up for both.
+func (k *Kubeswitch) SetContextNamespace(ctx, ns string) error {
+	// Error out if context is not valid.
+	if !k.IsValidContext(ctx) {
+		return fmt.Errorf("%w, %s", ErrInvalidContext, ctx)
+	}
+
+	prevCtx, prevNs := k.config.CurrentContext, k.CurrentNamespace()
+
+	// Run hook before switching so it can abort the switch.
+	if ctx != prevCtx {
+		if err := runHook(k.Hooks.PreContext, ctx, ns); err != nil {
+			return err
+		}
+	}
+
+	// Set current context and its default namespace.
+	k.config.CurrentContext = ctx
+	k.config.Contexts[ctx].Namespace = ns
+
+	// Remember namespace so it can be restored when switching back to context.
+	err := updateState(func(st *state) {
+		st.setNamespace(ctx, ns)
+		st.setContext(ctx)
+	})
+	if err != nil {
+		Logf("Unable to remember namespace %s: %s", ns, err)
+	}
+
+	// Skip rewriting session config or running a new shell if nothing changed.
+	if ctx == prevCtx && ns == prevNs {
+		Logf("Context %s and namespace %s are already set", ctx, ns)
+		return nil
+	}
+
+	// Create/update session config.
+	return k.setupSession(k.Hooks.PostContext, k.Hooks.PostNamespace)
+}
+
+// UnsetNamespace clears default namespace of current context so that
+// the cluster's default namespace is used.
+func (k *Kubeswitch) UnsetNamespace() error {
+	prevNs := k.CurrentNamespace()
+	if ctx, ok := k.config.Contexts[k.config.CurrentContext]; ok {
+		ctx.Namespace = ""
+	}
+
+	// Forget namespace so it's not restored when switching back to context.
+	cur := k.config.CurrentContext
+	if err := updateState(func(st *state) { delete(st.Namespaces, cur) }); err != nil {
+		Logf("Unable to forget namespace of context %s: %s", cur, err)
+	}
+
+	// Skip rewriting session config or running a new shell if nothing changed.
+	if prevNs == "" {
+		Logf("Namespace of context %s is already unset", cur)
+		return nil
+	}
+
+	// Create/update session config.
+	if err := k.setupSession(k.Hooks.PostNamespace); err != nil {
 		return err
 	}
 
@@ -227,6 +1221,10 @@ func (k *Kubeswitch) SetNamespace(ns string) error {
 
 // IsValidNamespace return true if namespace is one of the namespaces.
 func (k *Kubeswitch) IsValidNamespace(ns string) bool {
+	if k.namespaces == nil {
+		return false
+	}
+
 	for _, n := range k.namespaces.Items {
 		if n.Name == ns {
 			return true
@@ -245,37 +1243,239 @@ func IsActive() bool {
 	return false
 }
 
+// Depth returns how many session shells are nested. It uses EnvVarDepth
+// value and returns zero if not in a session shell.
+func Depth() int {
+	depth, _ := strconv.Atoi(os.Getenv(EnvVarDepth))
+	return depth
+}
+
 // Purge deletes temporary session files older than `days`.
-func Purge(days int) {
+func Purge(days int) error {
 	delTime := time.Now().AddDate(0, 0, days*-1)
 
+	sessDir, files, err := sessionFiles()
+	if err != nil {
+		return err
+	}
+
 	// Delete files that are older than `days` in session folder.
-	dir, _ := ioutil.ReadDir(sessionDir())
-	for _, i := range dir {
+	for _, i := range files {
 		if i.ModTime().Before(delTime) {
-			if err := os.Remove(sessionDir() + "/" + i.Name()); err != nil {
-				fmt.Println(err)
-			}
+			removeSessionFile(sessDir, i)
 		}
 	}
+
+	return nil
 }
 
-// writeConfig writes the unmarshaled config to disk.
-func (k *Kubeswitch) writeConfig(path string) error {
-	// Write session config file.
-	if err := clientcmd.WriteToFile(*k.config, path); err != nil {
+// PurgeAll deletes all temporary session files regardless of their age.
+func PurgeAll() error {
+	sessDir, files, err := sessionFiles()
+	if err != nil {
 		return err
 	}
 
+	for _, i := range files {
+		removeSessionFile(sessDir, i)
+	}
+
 	return nil
 }
 
-func init() {
-	// Create temporary session folder on startup if not exists.
-	if _, err := os.Stat(sessionDir()); err != nil {
-		if err := os.MkdirAll(sessionDir(), 0700); err != nil {
-			fmt.Println(err)
-			os.Exit(1)
+// PurgeBySize deletes oldest temporary session files until total size of
+// session files is at most limit bytes.
+func PurgeBySize(limit int64) error {
+	sessDir, files, err := sessionFiles()
+	if err != nil {
+		return err
+	}
+
+	var total int64
+	for _, i := range files {
+		total += i.Size()
+	}
+
+	// Files are sorted oldest first.
+	for _, i := range files {
+		if total <= limit {
+			break
 		}
+		removeSessionFile(sessDir, i)
+		total -= i.Size()
 	}
+
+	return nil
+}
+
+// PurgeKeep deletes temporary session files except the n most recent ones.
+func PurgeKeep(n int) error {
+	sessDir, files, err := sessionFiles()
+	if err != nil {
+		return err
+	}
+
+	// Files are sorted oldest first so newest files are at the end.
+	for i := 0; i < len(files)-n; i++ {
+		removeSessionFile(sessDir, files[i])
+	}
+
+	return nil
+}
+
+// sessionFiles returns session folder and session files in it sorted oldest
+// first. Only files named like session files are returned so that other files
+// in the folder are never purged.
+func sessionFiles() (string, []os.FileInfo, error) {
+	sessDir, err := sessionDir()
+	if err != nil {
+		return "", nil, err
+	}
+
+	dir, err := ioutil.ReadDir(sessDir)
+	if os.IsNotExist(err) {
+		return sessDir, nil, nil
+	} else if err != nil {
+		return "", nil, err
+	}
+
+	var files []os.FileInfo
+	for _, i := range dir {
+		if i.Mode().IsRegular() && strings.HasPrefix(i.Name(), "config_") {
+			files = append(files, i)
+		}
+	}
+	sort.SliceStable(files, func(i, j int) bool { return files[i].ModTime().Before(files[j].ModTime()) })
+
+	return sessDir, files, nil
+}
+
+// removeSessionFile deletes session file i in session folder sessDir.
+// Failures are printed so that purging continues with other files.
+func removeSessionFile(sessDir string, i os.FileInfo) {
+	Logf("Removing session file %s", i.Name())
+	if err := os.Remove(sessDir + "/" + i.Name()); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+	}
+}
+
+// WriteNamespaceSessions writes a session file for each namespace in nss with
+// the namespace set on current context and returns paths of the files. Loaded
+// config is left unchanged.
+func (k *Kubeswitch) WriteNamespaceSessions(nss []string) ([]string, error) {
+	if k.namespaces == nil {
+		return nil, ErrNamespacesNotLoaded
+	}
+
+	// Namespaces are set on current context so it must exist.
+	if _, ok := k.config.Contexts[k.config.CurrentContext]; !ok {
+		return nil, fmt.Errorf("%w, %s", ErrInvalidContext, k.config.CurrentContext)
+	}
+
+	for _, ns := range nss {
+		if !k.IsValidNamespace(ns) {
+			return nil, fmt.Errorf("%w, %s", ErrInvalidNamespace, ns)
+		}
+	}
+
+	dir, err := createSessionDir()
+	if err != nil {
+		return nil, err
+	}
+
+	var paths []string
+	for _, ns := range nss {
+		config := k.config.DeepCopy()
+		config.Contexts[config.CurrentContext].Namespace = ns
+
+		path := fmt.Sprintf("%s/config_%d_%s", dir, time.Now().UnixNano(), ns)
+		if err := (&Kubeswitch{config: config}).writeConfig(path); err != nil {
+			return nil, err
+		}
+		paths = append(paths, path)
+	}
+
+	return paths, nil
+}
+
+// WriteToFile writes the loaded config to path.
+func (k *Kubeswitch) WriteToFile(path string) error {
+	return k.writeConfig(path)
+}
+
+// Bytes returns loaded config serialized as YAML. It's what session files
+// and exported configs contain.
+func (k *Kubeswitch) Bytes() ([]byte, error) {
+	return clientcmd.Write(*k.config)
+}
+
+// writeConfig writes the unmarshaled config to disk atomically. The file is only
+// readable by the user since it contains credentials.
+func (k *Kubeswitch) writeConfig(path string) error {
+	start := time.Now()
+	defer func() {
+		d := time.Since(start)
+		LogEventf("write_config", map[string]interface{}{"path": path, "duration_ms": d.Milliseconds()}, "Wrote config %s in %s", path, d)
+	}()
+
+	data, err := k.Bytes()
+	if err != nil {
+		return err
+	}
+
+	return writeFile(path, data)
+}
+
+// writeFile writes data to a temporary file first and renames it to path so
+// that path is never left partially written. The folder of path is created if
+// not exists and the file is only readable by the user.
+func writeFile(path string, data []byte) error {
+	// Create folder of file if not exists.
+	dir := filepath.Dir(path)
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return err
+	}
+
+	// Write data to temporary file in the same folder so rename is atomic.
+	tmp, err := ioutil.TempFile(dir, filepath.Base(path)+".tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if err := tmp.Chmod(0600); err != nil {
+		tmp.Close()
+		return err
+	}
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+
+	// Replace file with temporary file.
+	return os.Rename(tmp.Name(), path)
+}
+
+// createSessionDir creates the session folder if not exists and returns
+// its path. It's only created when a session file is written so that
+// commands not starting sessions work on read-only filesystems.
+func createSessionDir() (string, error) {
+	dir, err := sessionDir()
+	if err != nil {
+		return "", err
+	}
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return "", fmt.Errorf("cannot create session folder %s: %w", dir, err)
+	}
+
+	// Ensure session folder is only accessible by user since session
+	// files contain credentials.
+	if err := os.Chmod(dir, 0700); err != nil {
+		return "", fmt.Errorf("cannot secure session folder %s: %w", dir, err)
+	}
+
+	return dir, nil
 }
@@ -16,12 +16,31 @@ limitations under the License.
 package kubeswitch
 
 import (
+	"context"
+	"errors"
 	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
 	"os"
+	"path/filepath"
 	"reflect"
+	"sort"
+	"strings"
+	"sync"
 	"testing"
+	"time"
 
 	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/kubernetes/fake"
+	"k8s.io/client-go/rest"
+	ktesting "k8s.io/client-go/testing"
+	"k8s.io/client-go/tools/clientcmd"
+	api "k8s.io/client-go/tools/clientcmd/api"
 )
 
 var ks *Kubeswitch
@@ -33,6 +52,18 @@ func TestNew(t *testing.T) {
 		t.Errorf("Expected error to be %v, got %v", nil, err)
 	}
 
+	// Test missing config.
+	os.Setenv(EnvVarConfig, filepath.Join(t.TempDir(), "config"))
+	if _, err := New(); err != ErrNoKubeconfig {
+		t.Errorf("Expected error to be %v, got %v", ErrNoKubeconfig, err)
+	}
+
+	// Test malformed config error mentions its path and format.
+	os.Setenv(EnvVarConfig, "../fixtures/config.yaml"+string(os.PathListSeparator)+"../fixtures/malformed.yaml")
+	if _, err := New(); err == nil || !strings.Contains(err.Error(), "../fixtures/malformed.yaml") || !strings.Contains(err.Error(), "YAML") {
+		t.Errorf("Expected error mentioning %s, got %v", "../fixtures/malformed.yaml", err)
+	}
+
 	// Test using YAML config.
 	os.Setenv(EnvVarConfig, "../fixtures/config.yaml")
 	if _, err := New(); err != nil {
@@ -40,6 +71,206 @@ func TestNew(t *testing.T) {
 	}
 }
 
+func TestLoadFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config")
+	ioutil.WriteFile(path, []byte(`{"clusters": [`), 0600)
+
+	// Test malformed JSON config is detected as JSON.
+	if _, err := loadFile(path); err == nil || !strings.Contains(err.Error(), "JSON") {
+		t.Errorf("Expected JSON syntax error, got %v", err)
+	}
+
+	// Test malformed config is returned as ErrMalformedConfig from New.
+	defer os.Setenv(EnvVarConfig, os.Getenv(EnvVarConfig))
+	os.Setenv(EnvVarConfig, path)
+	if _, err := New(); !errors.Is(err, ErrMalformedConfig) {
+		t.Errorf("Expected error to be %v, got %v", ErrMalformedConfig, err)
+	}
+
+	// Test valid config.
+	if _, err := loadFile("../fixtures/config.json"); err != nil {
+		t.Errorf("Expected error to be %v, got %v", nil, err)
+	}
+}
+
+func TestTimings(t *testing.T) {
+	var logs []string
+	origLogf := Logf
+	Logf = func(format string, a ...interface{}) { logs = append(logs, fmt.Sprintf(format, a...)) }
+	defer func() { Logf = origLogf }()
+
+	// Test elapsed time of loading and writing config is logged.
+	k, _ := New()
+	k.writeConfig(filepath.Join(t.TempDir(), "config"))
+	for _, prefix := range []string{"Loaded config in ", "Wrote config "} {
+		found := false
+		for _, l := range logs {
+			found = found || strings.HasPrefix(l, prefix)
+		}
+		if !found {
+			t.Errorf("Expected log starting with %q, got %v", prefix, logs)
+		}
+	}
+}
+
+func TestNewWithOptions(t *testing.T) {
+	origConfig := os.Getenv(EnvVarConfig)
+	defer os.Setenv(EnvVarConfig, origConfig)
+
+	// Create config that references a certificate file relative to it.
+	fixture, _ := clientcmd.LoadFromFile("../fixtures/config.yaml")
+	ca := fixture.Clusters["default"].CertificateAuthorityData
+	dir := t.TempDir()
+	ioutil.WriteFile(filepath.Join(dir, "ca.crt"), ca, 0600)
+	config := `apiVersion: v1
+kind: Config
+clusters:
+- cluster:
+    certificate-authority: ca.crt
+    server: https://127.0.0.1:6443
+  name: default
+contexts:
+- context:
+    cluster: default
+    user: default
+  name: default
+current-context: default
+users:
+- name: default
+  user:
+    token: abc
+`
+	ioutil.WriteFile(filepath.Join(dir, "config"), []byte(config), 0600)
+	os.Setenv(EnvVarConfig, filepath.Join(dir, "config"))
+
+	// Test certificate file is embedded by default.
+	k, err := NewWithOptions(Options{})
+	if err != nil {
+		t.Fatalf("Expected error to be %v, got %v", nil, err)
+	}
+	if c := k.config.Clusters["default"]; !reflect.DeepEqual(c.CertificateAuthorityData, ca) || c.CertificateAuthority != "" {
+		t.Errorf("Expected certificate to be embedded, got %v", c)
+	}
+
+	// Test certificate file reference is kept as absolute path.
+	k, err = NewWithOptions(Options{NoFlatten: true})
+	if err != nil {
+		t.Fatalf("Expected error to be %v, got %v", nil, err)
+	}
+	expected := filepath.Join(dir, "ca.crt")
+	if c := k.config.Clusters["default"]; c.CertificateAuthority != expected || len(c.CertificateAuthorityData) != 0 {
+		t.Errorf("Expected certificate reference to be %s, got %v", expected, c)
+	}
+
+	// Test client can still be built from config with references.
+	if _, err := k.client(); err != nil {
+		t.Errorf("Expected error to be %v, got %v", nil, err)
+	}
+}
+
+func TestClientCache(t *testing.T) {
+	k, _ := New()
+	k.config.Contexts["other"] = k.config.Contexts["default"]
+
+	// Test client is reused for the same context.
+	kube, err := k.client()
+	if err != nil {
+		t.Fatalf("Expected error to be %v, got %v", nil, err)
+	}
+	if cached, _ := k.client(); cached != kube {
+		t.Errorf("Expected client to be cached")
+	}
+
+	// Test client is rebuilt when current context changes.
+	k.config.CurrentContext = "other"
+	if rebuilt, _ := k.client(); rebuilt == kube || k.kubeContext != "other" {
+		t.Errorf("Expected client to be rebuilt for context %s, got %s", "other", k.kubeContext)
+	}
+}
+
+func TestCheckHealth(t *testing.T) {
+	status := http.StatusOK
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(status)
+	}))
+	defer srv.Close()
+
+	k, _ := New()
+	k.clientFactory = func(*rest.Config) (kubernetes.Interface, error) {
+		return kubernetes.NewForConfig(&rest.Config{Host: srv.URL})
+	}
+
+	// Test healthy cluster.
+	if err := k.CheckHealth(); err != nil {
+		t.Errorf("Expected error to be %v, got %v", nil, err)
+	}
+
+	// Test cluster responding with error status is reachable.
+	status = http.StatusForbidden
+	if err := k.CheckHealth(); err != nil {
+		t.Errorf("Expected error to be %v, got %v", nil, err)
+	}
+
+	// Test stopped cluster is unreachable.
+	srv.Close()
+	if err := k.CheckHealth(); !errors.Is(err, ErrUnreachable) {
+		t.Errorf("Expected error to be %v, got %v", ErrUnreachable, err)
+	}
+}
+
+func TestImpersonate(t *testing.T) {
+	k, _ := New()
+	k.As = "jane"
+	k.AsGroups = []string{"developers"}
+
+	// Test client impersonates user and groups.
+	restCfg, _, err := k.newClient(k.config.CurrentContext)
+	if err != nil {
+		t.Fatalf("Expected error to be %v, got %v", nil, err)
+	}
+	expected := rest.ImpersonationConfig{UserName: "jane", Groups: []string{"developers"}}
+	if !reflect.DeepEqual(restCfg.Impersonate, expected) {
+		t.Errorf("Expected impersonation to be %v, got %v", expected, restCfg.Impersonate)
+	}
+}
+
+func TestInsecure(t *testing.T) {
+	k, _ := New()
+	ctx := k.config.CurrentContext
+	cluster := k.config.Clusters[k.config.Contexts[ctx].Cluster]
+	cluster.InsecureSkipTLSVerify = true
+	cluster.CertificateAuthority = ""
+	cluster.CertificateAuthorityData = nil
+	cluster.ProxyURL = "http://proxy.example.com:3128"
+
+	// Test client honors insecure and proxy of cluster.
+	restCfg, _, err := k.newClient(ctx)
+	if err != nil {
+		t.Fatalf("Expected error to be %v, got %v", nil, err)
+	}
+	if !restCfg.Insecure {
+		t.Errorf("Expected insecure to be %v, got %v", true, restCfg.Insecure)
+	}
+	if restCfg.Proxy == nil {
+		t.Fatalf("Expected proxy to be set, got %v", nil)
+	}
+	req, _ := http.NewRequest(http.MethodGet, restCfg.Host, nil)
+	if u, err := restCfg.Proxy(req); err != nil || u.String() != cluster.ProxyURL {
+		t.Errorf("Expected proxy to be %s, got %v, %v", cluster.ProxyURL, u, err)
+	}
+
+	// Test insecure overrides certificate authority of cluster.
+	cluster.InsecureSkipTLSVerify = false
+	cluster.CertificateAuthorityData = []byte("ca")
+	k.Insecure = true
+	if restCfg, _, err = k.newClient(ctx); err != nil {
+		t.Fatalf("Expected error to be %v, got %v", nil, err)
+	}
+	if !restCfg.Insecure || restCfg.CAData != nil {
+		t.Errorf("Expected insecure without CA, got %v, %v", restCfg.Insecure, restCfg.CAData)
+	}
+}
+
 func TestListContexts(t *testing.T) {
 	ctxs := *ks.ListContexts()
 	if reflect.TypeOf(ctxs) != reflect.TypeOf([]string{}) {
@@ -51,6 +282,100 @@ func TestListContexts(t *testing.T) {
 	}
 }
 
+func TestContextDetails(t *testing.T) {
+	k, _ := New()
+	k.config.CurrentContext = "default"
+	k.config.Contexts["default"].Namespace = "kube-system"
+
+	infos := k.ContextDetails()
+	if len(infos) != len(k.config.Contexts) {
+		t.Errorf("Expected length is %v, got %v", len(k.config.Contexts), len(infos))
+	}
+	for _, info := range infos {
+		if info.Current != (info.Name == "default") {
+			t.Errorf("Expected current of %v to be %v, got %v", info.Name, info.Name == "default", info.Current)
+		}
+		if info.Name == "default" && info.Namespace != "kube-system" {
+			t.Errorf("Expected namespace to be %v, got %v", "kube-system", info.Namespace)
+		}
+	}
+}
+
+func TestFindContextsByServer(t *testing.T) {
+	k, _ := New()
+	server := k.config.Clusters[k.config.Contexts["default"].Cluster].Server
+
+	// Test full and partial server URLs match.
+	for _, s := range []string{server, "127.0.0.1"} {
+		if ctxs := k.FindContextsByServer(s); !reflect.DeepEqual(ctxs, []string{"default"}) {
+			t.Errorf("Expected contexts to be %v, got %v", []string{"default"}, ctxs)
+		}
+	}
+
+	// Test unknown server matches no contexts.
+	if ctxs := k.FindContextsByServer("https://10.0.0.1"); len(ctxs) != 0 {
+		t.Errorf("Expected no contexts, got %v", ctxs)
+	}
+}
+
+func TestContextsUsed(t *testing.T) {
+	dir := t.TempDir()
+	origKubeDir := kubeDir
+	kubeDir = func() (string, error) { return dir, nil }
+	defer func() { kubeDir = origKubeDir }()
+
+	k, _ := New()
+
+	// Test contexts never switched to have no last used time.
+	for _, info := range k.ContextDetails() {
+		if !info.LastUsed.IsZero() {
+			t.Errorf("Expected last used of %v to be zero, got %v", info.Name, info.LastUsed)
+		}
+	}
+
+	// Test switching to context records when it was last used.
+	before := time.Now()
+	recordContext("default")
+	for _, info := range k.ContextDetails() {
+		if info.Name == "default" && info.LastUsed.Before(before) {
+			t.Errorf("Expected last used of %v to be after %v, got %v", info.Name, before, info.LastUsed)
+		}
+	}
+}
+
+func TestRemoveDuplicateNames(t *testing.T) {
+	var logs []string
+	origLogf := Logf
+	Logf = func(format string, a ...interface{}) { logs = append(logs, fmt.Sprintf(format, a...)) }
+	defer func() { Logf = origLogf }()
+
+	// Test names only differing by whitespace are collapsed to trimmed name.
+	names := []string{" prod", "Prod", "dev", "prod", "prod "}
+	sort.Strings(names)
+	expected := []string{"Prod", "dev", "prod"}
+	if out := removeDuplicateNames(names); !reflect.DeepEqual(out, expected) {
+		t.Errorf("Expected names to be %v, got %v", expected, out)
+	}
+
+	// Test dropped duplicates and case collisions are logged.
+	if len(logs) != 3 {
+		t.Errorf("Expected %d logs, got %v", 3, logs)
+	}
+}
+
+func TestCurrentContext(t *testing.T) {
+	k, _ := New()
+	k.config.Contexts["default"].Namespace = "Namespace1"
+
+	// Test current context and its namespace are returned.
+	if c := k.CurrentContext(); c != "default" {
+		t.Errorf("Expected context to be %s, got %s", "default", c)
+	}
+	if n := k.CurrentNamespace(); n != "Namespace1" {
+		t.Errorf("Expected namespace to be %s, got %s", "Namespace1", n)
+	}
+}
+
 func TestIsValidContext(t *testing.T) {
 	// Testing with valid context.
 	if valid := ks.IsValidContext("default"); !valid {
@@ -63,6 +388,23 @@ func TestIsValidContext(t *testing.T) {
 	}
 }
 
+func TestInvalidErrors(t *testing.T) {
+	k, _ := New()
+	loadNamespaces(k, 1)
+
+	// Test invalid context error can be matched and names the context.
+	err := k.SetContext("invalid")
+	if !errors.Is(err, ErrInvalidContext) || err.Error() != "invalid context, invalid" {
+		t.Errorf("Expected error to be %v, got %v", ErrInvalidContext, err)
+	}
+
+	// Test invalid namespace error can be matched and names the namespace.
+	err = k.SetNamespace("invalid")
+	if !errors.Is(err, ErrInvalidNamespace) || err.Error() != "invalid namespace, invalid" {
+		t.Errorf("Expected error to be %v, got %v", ErrInvalidNamespace, err)
+	}
+}
+
 func TestListNamespaces(t *testing.T) {
 	size := 3
 	loadNamespaces(ks, size)
@@ -77,6 +419,97 @@ func TestListNamespaces(t *testing.T) {
 	}
 }
 
+func TestNamespacesNotLoaded(t *testing.T) {
+	k, _ := New()
+
+	// Test namespaces are empty before they are loaded.
+	if nss := *k.ListNamespaces(); len(nss) != 0 {
+		t.Errorf("Expected no namespaces, got %v", nss)
+	}
+	if infos := k.NamespaceDetails(); len(infos) != 0 {
+		t.Errorf("Expected no namespace details, got %v", infos)
+	}
+	if valid := k.IsValidNamespace("default"); valid {
+		t.Errorf("Expected valid to be %v, got %v", false, valid)
+	}
+
+	// Test setting namespace before loading them returns error.
+	if err := k.SetNamespace("default"); !errors.Is(err, ErrNamespacesNotLoaded) {
+		t.Errorf("Expected error to be %v, got %v", ErrNamespacesNotLoaded, err)
+	}
+}
+
+func TestCurrentNamespaceExists(t *testing.T) {
+	k, _ := New()
+	k.clientFactory = func(*rest.Config) (kubernetes.Interface, error) {
+		ns := &corev1.Namespace{}
+		ns.Name = "dev"
+		return fake.NewSimpleClientset(ns), nil
+	}
+
+	// Test context without namespace has nothing to check.
+	k.config.Contexts["default"].Namespace = ""
+	if exists, err := k.CurrentNamespaceExists(); err != nil || !exists {
+		t.Errorf("Expected exists to be %v, got %v, %v", true, exists, err)
+	}
+
+	// Test existing namespace.
+	k.config.Contexts["default"].Namespace = "dev"
+	if exists, err := k.CurrentNamespaceExists(); err != nil || !exists {
+		t.Errorf("Expected exists to be %v, got %v, %v", true, exists, err)
+	}
+
+	// Test deleted namespace.
+	k.config.Contexts["default"].Namespace = "deleted"
+	if exists, err := k.CurrentNamespaceExists(); err != nil || exists {
+		t.Errorf("Expected exists to be %v, got %v, %v", false, exists, err)
+	}
+}
+
+func TestListNamespacesRecent(t *testing.T) {
+	dir := t.TempDir()
+	origKubeDir := kubeDir
+	kubeDir = func() (string, error) { return dir, nil }
+	defer func() { kubeDir = origKubeDir }()
+
+	k, _ := New()
+	loadNamespaces(k, 4)
+	st, _ := loadState()
+	st.LastUsed[k.config.CurrentContext] = map[string]time.Time{
+		"Namespace2": time.Now().Add(-time.Hour),
+		"Namespace4": time.Now(),
+	}
+	st.save()
+
+	// Test namespaces are sorted by name by default.
+	expected := []string{"Namespace1", "Namespace2", "Namespace3", "Namespace4"}
+	if nss := *k.ListNamespaces(); !reflect.DeepEqual(nss, expected) {
+		t.Errorf("Expected namespaces to be %v, got %v", expected, nss)
+	}
+
+	// Test last selected namespaces are listed first.
+	k.SortRecent = true
+	expected = []string{"Namespace4", "Namespace2", "Namespace1", "Namespace3"}
+	if nss := *k.ListNamespaces(); !reflect.DeepEqual(nss, expected) {
+		t.Errorf("Expected namespaces to be %v, got %v", expected, nss)
+	}
+}
+
+func TestNamespaceDetails(t *testing.T) {
+	size := 2
+	loadNamespaces(ks, size)
+	ks.namespaces.Items[0].Status.Phase = corev1.NamespaceActive
+
+	infos := ks.NamespaceDetails()
+	if len(infos) != size {
+		t.Errorf("Expected length is %v, got %v", size, len(infos))
+	}
+
+	if infos[0].Name != "Namespace1" || infos[0].Status != "Active" {
+		t.Errorf("Expected details to be %v, got %v", "Namespace1 Active", infos[0])
+	}
+}
+
 func TestIsValidNamespace(t *testing.T) {
 	loadNamespaces(ks, 1)
 
@@ -91,6 +524,863 @@ func TestIsValidNamespace(t *testing.T) {
 	}
 }
 
+func TestLoadNamespacesContext(t *testing.T) {
+	k, _ := New()
+
+	// Test cancelled context aborts the namespace request.
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if err := k.LoadNamespacesContext(ctx); err == nil {
+		t.Errorf("Expected error for cancelled context, got %v", err)
+	}
+}
+
+func TestLoadNamespacesFake(t *testing.T) {
+	k, _ := New()
+	k.clientFactory = func(*rest.Config) (kubernetes.Interface, error) {
+		ns1, ns2 := &corev1.Namespace{}, &corev1.Namespace{}
+		ns1.Name, ns2.Name = "Namespace1", "Namespace2"
+		return fake.NewSimpleClientset(ns1, ns2), nil
+	}
+
+	// Test namespaces are loaded through client.
+	if err := k.LoadNamespaces(); err != nil {
+		t.Fatalf("Expected error to be %v, got %v", nil, err)
+	}
+	nss := *k.ListNamespaces()
+	sort.Strings(nss)
+	expected := []string{"Namespace1", "Namespace2"}
+	if !reflect.DeepEqual(nss, expected) {
+		t.Errorf("Expected namespaces to be %v, got %v", expected, nss)
+	}
+}
+
+func TestCreateNamespace(t *testing.T) {
+	k, _ := New()
+	k.config.Contexts["default"].Namespace = "existing"
+	activeSession(t)
+	var client *fake.Clientset
+	k.clientFactory = func(*rest.Config) (kubernetes.Interface, error) {
+		ns := &corev1.Namespace{}
+		ns.Name = "existing"
+		client = fake.NewSimpleClientset(ns)
+		return client, nil
+	}
+
+	// Test namespace is created and set.
+	if err := k.CreateNamespace("created"); err != nil {
+		t.Errorf("Expected error to be %v, got %v", nil, err)
+	}
+	if _, err := client.CoreV1().Namespaces().Get(context.Background(), "created", metav1.GetOptions{}); err != nil {
+		t.Errorf("Expected namespace %s to be created, got %v", "created", err)
+	}
+	if ns := k.CurrentNamespace(); ns != "created" {
+		t.Errorf("Expected namespace to be %v, got %v", "created", ns)
+	}
+
+	// Test existing namespace is set.
+	if err := k.CreateNamespace("existing"); err != nil {
+		t.Errorf("Expected error to be %v, got %v", nil, err)
+	}
+	if ns := k.CurrentNamespace(); ns != "existing" {
+		t.Errorf("Expected namespace to be %v, got %v", "existing", ns)
+	}
+
+	// Test forbidden error is kept.
+	client.PrependReactor("create", "namespaces", func(ktesting.Action) (bool, runtime.Object, error) {
+		return true, nil, apierrors.NewForbidden(corev1.Resource("namespaces"), "denied", nil)
+	})
+	if err := k.CreateNamespace("denied"); !apierrors.IsForbidden(err) {
+		t.Errorf("Expected forbidden error, got %v", err)
+	}
+	if k.IsValidNamespace("denied") {
+		t.Errorf("Expected namespace %s not to be added", "denied")
+	}
+}
+
+func TestWaitNamespace(t *testing.T) {
+	origInterval := waitInterval
+	waitInterval = time.Millisecond
+	defer func() { waitInterval = origInterval }()
+
+	// Create fake client with namespace that becomes active after polls.
+	var polls int
+	k, _ := New()
+	k.clientFactory = func(*rest.Config) (kubernetes.Interface, error) {
+		ns := &corev1.Namespace{}
+		ns.Name = "Namespace1"
+		c := fake.NewSimpleClientset(ns)
+		c.PrependReactor("get", "namespaces", func(a ktesting.Action) (bool, runtime.Object, error) {
+			if a.(ktesting.GetAction).GetName() != ns.Name {
+				return false, nil, nil
+			}
+			polls++
+			if polls >= 3 {
+				ns.Status.Phase = corev1.NamespaceActive
+			}
+			return true, ns, nil
+		})
+		return c, nil
+	}
+
+	// Test namespace is polled until active.
+	if err := k.WaitNamespace("Namespace1"); err != nil || polls != 3 {
+		t.Errorf("Expected %d polls without error, got %d, %v", 3, polls, err)
+	}
+
+	// Test polling stops when context is done.
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if err := k.WaitNamespaceContext(ctx, "missing"); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Expected error to be %v, got %v", context.DeadlineExceeded, err)
+	}
+}
+
+func TestLoadNamespacesRetry(t *testing.T) {
+	k, _ := New()
+	k.RetryAttempts = 3
+	k.RetryDelay = time.Millisecond
+
+	// Create fake client that fails listing with err for fails times.
+	var calls int
+	fakeClient := func(err error, fails int) func(*rest.Config) (kubernetes.Interface, error) {
+		return func(*rest.Config) (kubernetes.Interface, error) {
+			ns := &corev1.Namespace{}
+			ns.Name = "Namespace1"
+			c := fake.NewSimpleClientset(ns)
+			c.PrependReactor("list", "namespaces", func(ktesting.Action) (bool, runtime.Object, error) {
+				calls++
+				if calls <= fails {
+					return true, nil, err
+				}
+				return false, nil, nil
+			})
+			return c, nil
+		}
+	}
+
+	// Test transient errors are retried.
+	calls = 0
+	k.kube, k.clientFactory = nil, fakeClient(apierrors.NewServiceUnavailable("down"), 2)
+	if err := k.LoadNamespaces(); err != nil || calls != 3 {
+		t.Errorf("Expected %d calls without error, got %d, %v", 3, calls, err)
+	}
+
+	// Test retries stop after attempts.
+	calls = 0
+	k.kube, k.clientFactory = nil, fakeClient(apierrors.NewServiceUnavailable("down"), 5)
+	if err := k.LoadNamespaces(); err == nil || calls != 3 {
+		t.Errorf("Expected %d calls with error, got %d, %v", 3, calls, err)
+	}
+
+	// Test permission errors are not retried.
+	calls = 0
+	k.kube, k.clientFactory = nil, fakeClient(apierrors.NewForbidden(corev1.Resource("namespaces"), "", nil), 1)
+	if err := k.LoadNamespaces(); err == nil || calls != 1 {
+		t.Errorf("Expected %d call with error, got %d, %v", 1, calls, err)
+	}
+}
+
+func TestLoadNamespacesPermission(t *testing.T) {
+	k, _ := New()
+	fakeClient := func(err error) func(*rest.Config) (kubernetes.Interface, error) {
+		return func(*rest.Config) (kubernetes.Interface, error) {
+			c := fake.NewSimpleClientset()
+			c.PrependReactor("list", "namespaces", func(ktesting.Action) (bool, runtime.Object, error) {
+				return true, nil, err
+			})
+			return c, nil
+		}
+	}
+
+	// Test forbidden error names the context and keeps the cause.
+	k.kube, k.clientFactory = nil, fakeClient(apierrors.NewForbidden(corev1.Resource("namespaces"), "", nil))
+	err := k.LoadNamespaces()
+	if err == nil || !strings.Contains(err.Error(), "no permission to list namespaces in context default") || !apierrors.IsForbidden(errors.Unwrap(err)) {
+		t.Errorf("Expected forbidden error for context %s, got %v", "default", err)
+	}
+
+	// Test unauthorized error names the context.
+	k.kube, k.clientFactory = nil, fakeClient(apierrors.NewUnauthorized("expired"))
+	if err := k.LoadNamespaces(); err == nil || !strings.Contains(err.Error(), "unauthorized in context default") {
+		t.Errorf("Expected unauthorized error for context %s, got %v", "default", err)
+	}
+}
+
+func TestAddNamespace(t *testing.T) {
+	k, _ := New()
+
+	// Test namespace is valid after adding without loading namespaces.
+	k.AddNamespace("Namespace1")
+	if !k.IsValidNamespace("Namespace1") {
+		t.Errorf("Expected namespace %s to be valid", "Namespace1")
+	}
+
+	// Test adding an existing namespace doesn't duplicate it.
+	k.AddNamespace("Namespace1")
+	if nss := *k.ListNamespaces(); len(nss) != 1 {
+		t.Errorf("Expected length is %v, got %v", 1, len(nss))
+	}
+}
+
+func TestLoadNamespacesForContexts(t *testing.T) {
+	k, _ := New()
+	other := k.config.Clusters["default"].DeepCopy()
+	other.Server = "https://other"
+	k.config.Clusters["other"] = other
+	k.config.Contexts["other"] = &api.Context{Cluster: "other", AuthInfo: "default"}
+
+	// Create fake clients with namespaces named after their cluster.
+	k.clientFactory = func(restCfg *rest.Config) (kubernetes.Interface, error) {
+		ns := &corev1.Namespace{}
+		ns.Name = "default-ns"
+		if restCfg.Host == "https://other" {
+			ns.Name = "other-ns"
+		}
+		return fake.NewSimpleClientset(ns), nil
+	}
+
+	// Test namespaces of each context are loaded.
+	nss, err := k.LoadNamespacesForContexts([]string{"default", "other"})
+	expected := map[string][]string{"default": {"default-ns"}, "other": {"other-ns"}}
+	if err != nil || !reflect.DeepEqual(nss, expected) {
+		t.Errorf("Expected namespaces to be %v, got %v, %v", expected, nss, err)
+	}
+
+	// Test invalid context returns error with namespaces of other contexts.
+	nss, err = k.LoadNamespacesForContexts([]string{"default", "invalid"})
+	if !errors.Is(err, ErrInvalidContext) || len(nss) != 1 {
+		t.Errorf("Expected error to be %v with namespaces of %d context, got %v, %v", ErrInvalidContext, 1, nss, err)
+	}
+}
+
+func TestLoadNamespacesForContextsPartial(t *testing.T) {
+	k, _ := New()
+	k.Concurrency = 2
+	for _, name := range []string{"east", "west", "down"} {
+		cluster := k.config.Clusters["default"].DeepCopy()
+		cluster.Server = "https://" + name
+		k.config.Clusters[name] = cluster
+		k.config.Contexts[name] = &api.Context{Cluster: name, AuthInfo: "default"}
+	}
+
+	// Create fake clients where cluster down is unreachable.
+	var mu sync.Mutex
+	running, maxRunning := 0, 0
+	k.clientFactory = func(restCfg *rest.Config) (kubernetes.Interface, error) {
+		ns := &corev1.Namespace{}
+		ns.Name = strings.TrimPrefix(restCfg.Host, "https://") + "-ns"
+		c := fake.NewSimpleClientset(ns)
+		c.PrependReactor("list", "namespaces", func(ktesting.Action) (bool, runtime.Object, error) {
+			mu.Lock()
+			running++
+			if running > maxRunning {
+				maxRunning = running
+			}
+			mu.Unlock()
+			time.Sleep(10 * time.Millisecond)
+			mu.Lock()
+			running--
+			mu.Unlock()
+
+			if restCfg.Host == "https://down" {
+				return true, nil, apierrors.NewServiceUnavailable("down")
+			}
+			return false, nil, nil
+		})
+		return c, nil
+	}
+
+	// Test unreachable context doesn't abort other contexts.
+	nss, err := k.LoadNamespacesForContexts([]string{"east", "west", "down", "default"})
+	expected := map[string][]string{"east": {"east-ns"}, "west": {"west-ns"}, "default": {"127.0.0.1:6443-ns"}}
+	if !apierrors.IsServiceUnavailable(err) || !reflect.DeepEqual(nss, expected) {
+		t.Errorf("Expected namespaces to be %v with error, got %v, %v", expected, nss, err)
+	}
+
+	// Test namespaces are fetched from up to Concurrency contexts at once.
+	if maxRunning > k.Concurrency {
+		t.Errorf("Expected at most %d concurrent requests, got %d", k.Concurrency, maxRunning)
+	}
+
+	// Test cancelled ctx aborts requests of all contexts.
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	k.RetryAttempts = 3
+	k.RetryDelay = time.Second
+	if _, err := k.LoadNamespacesForContextsContext(ctx, []string{"down"}); err == nil {
+		t.Errorf("Expected error for cancelled context, got %v", err)
+	}
+}
+
+func TestSetContextNamespace(t *testing.T) {
+	k, _ := New()
+	k.config.Contexts["default"].Namespace = "Namespace1"
+
+	dir := t.TempDir()
+	origKubeDir := kubeDir
+	kubeDir = func() (string, error) { return dir, nil }
+	defer func() { kubeDir = origKubeDir }()
+	os.Unsetenv(EnvVarActive)
+
+	// Use an invalid shell so that spawning a session returns an error.
+	k.Shell = "/path/to/not/exists/shell"
+
+	// Test setting current context and namespace doesn't spawn a session.
+	if err := k.SetContextNamespace("default", "Namespace1"); err != nil {
+		t.Errorf("Expected error to be %v, got %v", nil, err)
+	}
+
+	// Test changing namespace attempts to spawn a session.
+	if err := k.SetContextNamespace("default", "Namespace2"); err == nil {
+		t.Errorf("Expected error for invalid shell, got %v", err)
+	}
+	if ns := k.CurrentNamespace(); ns != "Namespace2" {
+		t.Errorf("Expected namespace to be %s, got %s", "Namespace2", ns)
+	}
+
+	// Test invalid context.
+	if err := k.SetContextNamespace("invalid", "Namespace1"); !errors.Is(err, ErrInvalidContext) {
+		t.Errorf("Expected error to be %v, got %v", ErrInvalidContext, err)
+	}
+}
+
+func TestSetTrimmed(t *testing.T) {
+	k, _ := New()
+	k.AddNamespace("kube-system")
+	activeSession(t)
+
+	var logs []string
+	origLogf := Logf
+	Logf = func(format string, a ...interface{}) { logs = append(logs, fmt.Sprintf(format, a...)) }
+	defer func() { Logf = origLogf }()
+
+	// Test surrounding whitespace is trimmed from names.
+	if err := k.SetNamespace(" kube-system "); err != nil || k.CurrentNamespace() != "kube-system" {
+		t.Errorf("Expected namespace to be %v, got %v, %v", "kube-system", k.CurrentNamespace(), err)
+	}
+	if err := k.SetContext("default\n"); err != nil || k.CurrentContext() != "default" {
+		t.Errorf("Expected context to be %v, got %v, %v", "default", k.CurrentContext(), err)
+	}
+
+	// Test trimming is logged.
+	expected := `Trimmed namespace " kube-system " to "kube-system"`
+	if len(logs) == 0 || logs[0] != expected {
+		t.Errorf("Expected log to be %v, got %v", expected, logs)
+	}
+}
+
+func TestRestoreNamespace(t *testing.T) {
+	dir := t.TempDir()
+	origKubeDir := kubeDir
+	kubeDir = func() (string, error) { return dir, nil }
+	defer func() { kubeDir = origKubeDir }()
+
+	k, _ := New()
+	st, _ := loadState()
+	st.Namespaces["default"] = "kube-system"
+	if err := st.save(); err != nil {
+		t.Errorf("Expected error to be %v, got %v", nil, err)
+	}
+
+	// Test remembered namespace is restored for context without namespace.
+	k.restoreNamespace()
+	if ns := k.config.Contexts["default"].Namespace; ns != "kube-system" {
+		t.Errorf("Expected namespace to be %v, got %v", "kube-system", ns)
+	}
+
+	// Test existing namespace of context is kept.
+	k.config.Contexts["default"].Namespace = "default"
+	k.restoreNamespace()
+	if ns := k.config.Contexts["default"].Namespace; ns != "default" {
+		t.Errorf("Expected namespace to be %v, got %v", "default", ns)
+	}
+}
+
+func TestRestoreNamespaceCorruptState(t *testing.T) {
+	k, _ := New()
+	activeSession(t)
+	path, _ := stateFile()
+	ioutil.WriteFile(path, []byte("{corrupt"), 0600)
+
+	// Test switching to context without namespace doesn't fail.
+	k.config.Contexts["default"].Namespace = ""
+	if err := k.SetContext("default"); err != nil {
+		t.Errorf("Expected error to be %v, got %v", nil, err)
+	}
+	if ns := k.CurrentNamespace(); ns != "" {
+		t.Errorf("Expected namespace to be %q, got %q", "", ns)
+	}
+}
+
+func TestSetNoChange(t *testing.T) {
+	k, _ := New()
+	loadNamespaces(k, 1)
+	kubePath := activeSession(t)
+
+	// Test setting current context doesn't write session config.
+	if err := k.SetContext("default"); err != nil {
+		t.Errorf("Expected error to be %v, got %v", nil, err)
+	}
+	if _, err := os.Stat(kubePath); !os.IsNotExist(err) {
+		t.Errorf("Expected session config not to be written, got %v", err)
+	}
+
+	// Test setting new namespace writes session config.
+	if err := k.SetNamespace("Namespace1"); err != nil {
+		t.Errorf("Expected error to be %v, got %v", nil, err)
+	}
+	if _, err := os.Stat(kubePath); err != nil {
+		t.Errorf("Expected session config to be written, got %v", err)
+	}
+
+	// Test setting current namespace doesn't write session config.
+	os.Remove(kubePath)
+	if err := k.SetNamespace("Namespace1"); err != nil {
+		t.Errorf("Expected error to be %v, got %v", nil, err)
+	}
+	if _, err := os.Stat(kubePath); !os.IsNotExist(err) {
+		t.Errorf("Expected session config not to be written, got %v", err)
+	}
+
+	// Test unsetting namespace writes session config.
+	if err := k.UnsetNamespace(); err != nil {
+		t.Errorf("Expected error to be %v, got %v", nil, err)
+	}
+	if _, err := os.Stat(kubePath); err != nil {
+		t.Errorf("Expected session config to be written, got %v", err)
+	}
+
+	// Test unsetting unset namespace doesn't write session config.
+	os.Remove(kubePath)
+	if err := k.UnsetNamespace(); err != nil {
+		t.Errorf("Expected error to be %v, got %v", nil, err)
+	}
+	if _, err := os.Stat(kubePath); !os.IsNotExist(err) {
+		t.Errorf("Expected session config not to be written, got %v", err)
+	}
+}
+
+func TestSetNoChangeNoSession(t *testing.T) {
+	k, _ := New()
+	loadNamespaces(k, 1)
+	k.config.Contexts["default"].Namespace = "Namespace1"
+
+	dir := t.TempDir()
+	origKubeDir := kubeDir
+	kubeDir = func() (string, error) { return dir, nil }
+	defer func() { kubeDir = origKubeDir }()
+	os.Unsetenv(EnvVarActive)
+
+	// Use an invalid shell so that spawning a session returns an error.
+	k.Shell = "/path/to/not/exists/shell"
+
+	// Test setting current context and namespace doesn't spawn a session.
+	if err := k.SetContext("default"); err != nil {
+		t.Errorf("Expected error to be %v, got %v", nil, err)
+	}
+	if err := k.SetNamespace("Namespace1"); err != nil {
+		t.Errorf("Expected error to be %v, got %v", nil, err)
+	}
+
+	// Test changing namespace attempts to spawn a session.
+	loadNamespaces(k, 2)
+	if err := k.SetNamespace("Namespace2"); err == nil {
+		t.Errorf("Expected error for invalid shell, got %v", err)
+	}
+}
+
+func TestNoSession(t *testing.T) {
+	dir := t.TempDir()
+	origKubeDir := kubeDir
+	kubeDir = func() (string, error) { return dir, nil }
+	defer func() { kubeDir = origKubeDir }()
+	os.Unsetenv(EnvVarActive)
+
+	// Create config file with a second context to switch to.
+	config, _ := clientcmd.LoadFromFile("../fixtures/config.yaml")
+	config.Contexts["other"] = config.Contexts["default"].DeepCopy()
+	path := filepath.Join(dir, "config")
+	clientcmd.WriteToFile(*config, path)
+	origConfig := os.Getenv(EnvVarConfig)
+	os.Setenv(EnvVarConfig, path)
+	defer os.Setenv(EnvVarConfig, origConfig)
+
+	var warns []string
+	origWarnf := Warnf
+	Warnf = func(format string, a ...interface{}) { warns = append(warns, fmt.Sprintf(format, a...)) }
+	defer func() { Warnf = origWarnf }()
+
+	k, _ := New()
+	k.NoSession = true
+	k.Shell = "/path/to/not/exists/shell"
+
+	// Test context and namespace are changed in config file.
+	if err := k.SetContext("other"); err != nil {
+		t.Errorf("Expected error to be %v, got %v", nil, err)
+	}
+	loadNamespaces(k, 1)
+	if err := k.SetNamespace("Namespace1"); err != nil {
+		t.Errorf("Expected error to be %v, got %v", nil, err)
+	}
+	config, _ = clientcmd.LoadFromFile(path)
+	if config.CurrentContext != "other" || config.Contexts["other"].Namespace != "Namespace1" {
+		t.Errorf("Expected config file to have context %s and namespace %s, got %s and %s",
+			"other", "Namespace1", config.CurrentContext, config.Contexts["other"].Namespace)
+	}
+
+	// Test no session file is written.
+	if files, _ := filepath.Glob(filepath.Join(dir, "tmp", "config_*")); len(files) != 0 {
+		t.Errorf("Expected no session files, got %v", files)
+	}
+
+	// Test changing config files in place is warned through Warnf.
+	if len(warns) != 2 {
+		t.Errorf("Expected %d warnings, got %v", 2, warns)
+	}
+}
+
+func TestWriteConfig(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config")
+
+	// Test config is written completely.
+	if err := ks.writeConfig(path); err != nil {
+		t.Errorf("Expected error to be %v, got %v", nil, err)
+	}
+	config, err := clientcmd.LoadFromFile(path)
+	if err != nil {
+		t.Errorf("Expected error to be %v, got %v", nil, err)
+	} else if _, ok := config.Contexts["default"]; !ok {
+		t.Errorf("Expected written config to contain context %v", "default")
+	}
+
+	// Test config is only readable by user.
+	if info, _ := os.Stat(path); info.Mode().Perm() != 0600 {
+		t.Errorf("Expected mode to be %v, got %v", os.FileMode(0600), info.Mode().Perm())
+	}
+
+	// Test no temporary files are left behind.
+	if files, _ := filepath.Glob(path + ".tmp*"); len(files) != 0 {
+		t.Errorf("Expected no temporary files, got %v", files)
+	}
+}
+
+func TestBytes(t *testing.T) {
+	k, _ := New()
+	k.config.Contexts["default"].Namespace = "kube-system"
+
+	// Test serialized config round-trips through clientcmd.
+	data, err := k.Bytes()
+	if err != nil {
+		t.Fatalf("Expected error to be %v, got %v", nil, err)
+	}
+	config, err := clientcmd.Load(data)
+	if err != nil {
+		t.Fatalf("Expected error to be %v, got %v", nil, err)
+	}
+	if config.CurrentContext != k.config.CurrentContext || config.Contexts["default"].Namespace != "kube-system" {
+		t.Errorf("Expected config to be %v, got %v", k.config, config)
+	}
+	if len(config.Clusters) != len(k.config.Clusters) || len(config.AuthInfos) != len(k.config.AuthInfos) {
+		t.Errorf("Expected config to be %v, got %v", k.config, config)
+	}
+}
+
+func TestSessionConfigMode(t *testing.T) {
+	k, _ := New()
+	k.NoShell = true
+	os.Unsetenv(EnvVarActive)
+	origConfig := os.Getenv(EnvVarConfig)
+	defer os.Setenv(EnvVarConfig, origConfig)
+	defer os.Unsetenv(EnvVarActive)
+	defer os.Unsetenv(EnvVarContext)
+	defer os.Unsetenv(EnvVarNamespace)
+
+	dir := t.TempDir()
+	origKubeDir := kubeDir
+	kubeDir = func() (string, error) { return dir, nil }
+	defer func() { kubeDir = origKubeDir }()
+
+	// Test freshly written session config is only readable by user.
+	if err := k.setupSession(); err != nil {
+		t.Errorf("Expected error to be %v, got %v", nil, err)
+	}
+	info, err := os.Stat(os.Getenv(EnvVarConfig))
+	if err != nil {
+		t.Errorf("Expected error to be %v, got %v", nil, err)
+	} else if info.Mode().Perm() != 0600 {
+		t.Errorf("Expected mode to be %v, got %v", os.FileMode(0600), info.Mode().Perm())
+	}
+
+	// Test context and namespace are exported for session.
+	if ctx := os.Getenv(EnvVarContext); ctx != k.CurrentContext() {
+		t.Errorf("Expected %s to be %v, got %v", EnvVarContext, k.CurrentContext(), ctx)
+	}
+}
+
+func TestCreateSessionDir(t *testing.T) {
+	dir := t.TempDir()
+	origKubeDir := kubeDir
+	defer func() { kubeDir = origKubeDir }()
+
+	// Test session folder is created only accessible by user.
+	kubeDir = func() (string, error) { return dir, nil }
+	if _, err := createSessionDir(); err != nil {
+		t.Errorf("Expected error to be %v, got %v", nil, err)
+	}
+	if info, err := os.Stat(filepath.Join(dir, "tmp")); err != nil || info.Mode().Perm() != 0700 {
+		t.Errorf("Expected session folder with mode %v, got %v, %v", os.FileMode(0700), info, err)
+	}
+
+	// Test error is returned when session folder can't be created.
+	file := filepath.Join(dir, "file")
+	ioutil.WriteFile(file, nil, 0600)
+	kubeDir = func() (string, error) { return file, nil }
+	if _, err := createSessionDir(); err == nil {
+		t.Errorf("Expected error for uncreatable session folder, got %v", err)
+	}
+	k, _ := New()
+	k.NoShell = true
+	os.Unsetenv(EnvVarActive)
+	defer os.Setenv(EnvVarConfig, os.Getenv(EnvVarConfig))
+	if err := k.setupSession(); err == nil {
+		t.Errorf("Expected error for uncreatable session folder, got %v", err)
+	}
+}
+
+func TestWriteNamespaceSessions(t *testing.T) {
+	k, _ := New()
+	k.AddNamespace("dev")
+	k.AddNamespace("qa")
+	ctx := k.config.CurrentContext
+	origNs := k.config.Contexts[ctx].Namespace
+
+	dir := t.TempDir()
+	origKubeDir := kubeDir
+	kubeDir = func() (string, error) { return dir, nil }
+	defer func() { kubeDir = origKubeDir }()
+
+	// Test a session file is written for each namespace.
+	paths, err := k.WriteNamespaceSessions([]string{"dev", "qa"})
+	if err != nil || len(paths) != 2 {
+		t.Fatalf("Expected %d paths, got %v, %v", 2, paths, err)
+	}
+	for i, ns := range []string{"dev", "qa"} {
+		config, err := clientcmd.LoadFromFile(paths[i])
+		if err != nil {
+			t.Fatalf("Expected error to be %v, got %v", nil, err)
+		}
+		if n := config.Contexts[ctx].Namespace; n != ns {
+			t.Errorf("Expected namespace to be %v, got %v", ns, n)
+		}
+	}
+
+	// Test loaded config is unchanged.
+	if n := k.config.Contexts[ctx].Namespace; n != origNs {
+		t.Errorf("Expected namespace to be %v, got %v", origNs, n)
+	}
+
+	// Test invalid namespace returns error.
+	if _, err := k.WriteNamespaceSessions([]string{"dev", "invalid"}); !errors.Is(err, ErrInvalidNamespace) {
+		t.Errorf("Expected error to be %v, got %v", ErrInvalidNamespace, err)
+	}
+
+	// Test missing current context returns error instead of panicking.
+	k.config.CurrentContext = ""
+	if _, err := k.WriteNamespaceSessions([]string{"dev"}); !errors.Is(err, ErrInvalidContext) {
+		t.Errorf("Expected error to be %v, got %v", ErrInvalidContext, err)
+	}
+}
+
+func TestSessionByContext(t *testing.T) {
+	k, _ := New()
+	k.NoShell = true
+	k.SessionByContext = true
+	os.Unsetenv(EnvVarActive)
+	defer os.Setenv(EnvVarConfig, os.Getenv(EnvVarConfig))
+	defer os.Unsetenv(EnvVarActive)
+
+	dir := t.TempDir()
+	origKubeDir := kubeDir
+	kubeDir = func() (string, error) { return dir, nil }
+	defer func() { kubeDir = origKubeDir }()
+
+	// Test session file is named after context and reused.
+	expected := filepath.Join(dir, "tmp", "config_default")
+	for i := 0; i < 2; i++ {
+		if err := k.setupSession(); err != nil {
+			t.Errorf("Expected error to be %v, got %v", nil, err)
+		}
+		if path := os.Getenv(EnvVarConfig); path != expected {
+			t.Errorf("Expected session file to be %v, got %v", expected, path)
+		}
+		os.Unsetenv(EnvVarActive)
+	}
+
+	// Test unsafe characters are replaced without collisions.
+	a, b := sessionName("arn:aws:eks/prod"), sessionName("arn_aws_eks_prod")
+	if strings.ContainsAny(a, ":/") || a == b {
+		t.Errorf("Expected distinct safe names, got %v and %v", a, b)
+	}
+	if n := sessionName("../../etc"); strings.Contains(n, "/") {
+		t.Errorf("Expected name without path separators, got %v", n)
+	}
+}
+
+func TestPurge(t *testing.T) {
+	dir := t.TempDir()
+	origKubeDir := kubeDir
+	kubeDir = func() (string, error) { return dir, nil }
+	defer func() { kubeDir = origKubeDir }()
+
+	// Test missing session folder is nothing to purge.
+	if err := PurgeAll(); err != nil {
+		t.Errorf("Expected error to be %v, got %v", nil, err)
+	}
+
+	sessDir := filepath.Join(dir, "tmp")
+	old := sessionFile(t, sessDir, "config_1", 0, 3*24*time.Hour)
+	recent := sessionFile(t, sessDir, "config_2", 0, time.Hour)
+	other := sessionFile(t, sessDir, "notes.txt", 0, 3*24*time.Hour)
+
+	// Test only session files older than days are deleted.
+	if err := Purge(2); err != nil {
+		t.Errorf("Expected error to be %v, got %v", nil, err)
+	}
+	if _, err := os.Stat(old); !os.IsNotExist(err) {
+		t.Errorf("Expected %s to be deleted, got %v", old, err)
+	}
+	if _, err := os.Stat(recent); err != nil {
+		t.Errorf("Expected %s to be kept, got %v", recent, err)
+	}
+
+	// Test all session files are deleted but other files are kept.
+	if err := PurgeAll(); err != nil {
+		t.Errorf("Expected error to be %v, got %v", nil, err)
+	}
+	if _, err := os.Stat(recent); !os.IsNotExist(err) {
+		t.Errorf("Expected %s to be deleted, got %v", recent, err)
+	}
+	if _, err := os.Stat(other); err != nil {
+		t.Errorf("Expected %s to be kept, got %v", other, err)
+	}
+}
+
+func TestPurgeBySize(t *testing.T) {
+	dir := t.TempDir()
+	origKubeDir := kubeDir
+	kubeDir = func() (string, error) { return dir, nil }
+	defer func() { kubeDir = origKubeDir }()
+
+	sessDir := filepath.Join(dir, "tmp")
+	oldest := sessionFile(t, sessDir, "config_1", 100, 3*time.Hour)
+	older := sessionFile(t, sessDir, "config_2", 100, 2*time.Hour)
+	newest := sessionFile(t, sessDir, "config_3", 100, time.Hour)
+
+	// Test oldest files are deleted until total size is under limit.
+	if err := PurgeBySize(150); err != nil {
+		t.Errorf("Expected error to be %v, got %v", nil, err)
+	}
+	for path, kept := range map[string]bool{oldest: false, older: false, newest: true} {
+		if _, err := os.Stat(path); (err == nil) != kept {
+			t.Errorf("Expected %s to be kept %v, got %v", path, kept, err)
+		}
+	}
+
+	// Test nothing is deleted when under limit.
+	if err := PurgeBySize(100); err != nil {
+		t.Errorf("Expected error to be %v, got %v", nil, err)
+	}
+	if _, err := os.Stat(newest); err != nil {
+		t.Errorf("Expected %s to be kept, got %v", newest, err)
+	}
+}
+
+func TestPurgeKeep(t *testing.T) {
+	dir := t.TempDir()
+	origKubeDir := kubeDir
+	kubeDir = func() (string, error) { return dir, nil }
+	defer func() { kubeDir = origKubeDir }()
+
+	sessDir := filepath.Join(dir, "tmp")
+	var paths []string
+	for i := 1; i <= 5; i++ {
+		paths = append(paths, sessionFile(t, sessDir, fmt.Sprintf("config_%d", i), 0, time.Duration(6-i)*time.Hour))
+	}
+
+	// Test exactly n newest files are kept.
+	if err := PurgeKeep(2); err != nil {
+		t.Errorf("Expected error to be %v, got %v", nil, err)
+	}
+	_, files, _ := sessionFiles()
+	if len(files) != 2 {
+		t.Errorf("Expected %d files, got %d", 2, len(files))
+	}
+	for i, path := range paths {
+		if _, err := os.Stat(path); (err == nil) != (i >= 3) {
+			t.Errorf("Expected %s to be kept %v, got %v", path, i >= 3, err)
+		}
+	}
+
+	// Test keeping more files than exist deletes nothing.
+	if err := PurgeKeep(5); err != nil {
+		t.Errorf("Expected error to be %v, got %v", nil, err)
+	}
+	if _, files, _ := sessionFiles(); len(files) != 2 {
+		t.Errorf("Expected %d files, got %d", 2, len(files))
+	}
+}
+
+func TestKubeDirError(t *testing.T) {
+	origKubeDir := kubeDir
+	defer func() { kubeDir = origKubeDir }()
+	kubeDir = func() (string, error) { return "", errors.New("no home") }
+
+	// Test error of finding kube folder is returned instead of exiting.
+	if err := Purge(2); err == nil {
+		t.Errorf("Expected error for missing kube folder, got %v", err)
+	}
+	if _, err := Import("../fixtures/config.yaml", ""); err == nil {
+		t.Errorf("Expected error for missing kube folder, got %v", err)
+	}
+	if _, err := loadState(); err == nil {
+		t.Errorf("Expected error for missing kube folder, got %v", err)
+	}
+
+	k, _ := New()
+	k.NoShell = true
+	os.Unsetenv(EnvVarActive)
+	defer os.Setenv(EnvVarConfig, os.Getenv(EnvVarConfig))
+	if err := k.setupSession(); err == nil {
+		t.Errorf("Expected error for missing kube folder, got %v", err)
+	}
+}
+
+func TestMaxDepth(t *testing.T) {
+	k, _ := New()
+	k.Shell = "/bin/sh"
+	k.MaxDepth = 2
+	os.Unsetenv(EnvVarActive)
+	origConfig := os.Getenv(EnvVarConfig)
+	defer os.Setenv(EnvVarConfig, origConfig)
+	defer os.Unsetenv(EnvVarDepth)
+
+	// Test session shell is not run beyond max depth.
+	os.Setenv(EnvVarDepth, "2")
+	if err := k.setupSession(); err == nil {
+		t.Errorf("Expected error for exceeding max depth, got %v", err)
+	}
+
+	// Test depth is read from env var.
+	if d := Depth(); d != 2 {
+		t.Errorf("Expected depth to be %d, got %d", 2, d)
+	}
+	os.Unsetenv(EnvVarDepth)
+	if d := Depth(); d != 0 {
+		t.Errorf("Expected depth to be %d, got %d", 0, d)
+	}
+}
+
 func TestIsActive(t *testing.T) {
 	// Test with active session.
 	os.Setenv(EnvVarActive, "TRUE")
@@ -117,6 +1407,39 @@ func init() {
 	ks, _ = New()
 }
 
+// Setup an active session in a temporary kube folder for testing.
+// Returns the session config path.
+func activeSession(t *testing.T) string {
+	dir := t.TempDir()
+	kubePath := filepath.Join(dir, "config")
+
+	origKubeDir := kubeDir
+	origConfig := os.Getenv(EnvVarConfig)
+	kubeDir = func() (string, error) { return dir, nil }
+	os.Setenv(EnvVarActive, "TRUE")
+	os.Setenv(EnvVarConfig, kubePath)
+
+	t.Cleanup(func() {
+		kubeDir = origKubeDir
+		os.Unsetenv(EnvVarActive)
+		os.Setenv(EnvVarConfig, origConfig)
+	})
+
+	return kubePath
+}
+
+// sessionFile creates a file named name of size bytes in dir modified age ago.
+func sessionFile(t *testing.T, dir, name string, size int, age time.Duration) string {
+	path := filepath.Join(dir, name)
+	os.MkdirAll(dir, 0700)
+	if err := ioutil.WriteFile(path, make([]byte, size), 0600); err != nil {
+		t.Fatalf("Expected error to be %v, got %v", nil, err)
+	}
+	modTime := time.Now().Add(-age)
+	os.Chtimes(path, modTime, modTime)
+	return path
+}
+
 // Load sample namespaces for testing.
 func loadNamespaces(k *Kubeswitch, size int) {
 	var nss []corev1.Namespace
@@ -129,3 +1452,139 @@ func loadNamespaces(k *Kubeswitch, size int) {
 	nsList.Items = nss
 	k.namespaces = &nsList
 }
+
+func TestStateSave(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "home", ".kube")
+	origKubeDir := kubeDir
+	kubeDir = func() (string, error) { return dir, nil }
+	defer func() { kubeDir = origKubeDir }()
+
+	// Test missing kube folder is created.
+	st, _ := loadState()
+	st.setContext("default")
+	if err := st.save(); err != nil {
+		t.Errorf("Expected error to be %v, got %v", nil, err)
+	}
+	if st, _ := loadState(); st.ContextsUsed["default"].IsZero() {
+		t.Errorf("Expected context %s to be recorded", "default")
+	}
+
+	// Test no temporary file is left behind.
+	if files, _ := ioutil.ReadDir(dir); len(files) != 1 {
+		t.Errorf("Expected %d files, got %d", 1, len(files))
+	}
+}
+
+func TestSetContextStateError(t *testing.T) {
+	k, _ := New()
+	k.config.Contexts["default"].Namespace = "default"
+	activeSession(t)
+
+	// Point state file inside a regular file so it can't be written.
+	file := filepath.Join(t.TempDir(), "file")
+	ioutil.WriteFile(file, nil, 0600)
+	origStateFile := stateFile
+	stateFile = func() (string, error) { return filepath.Join(file, "kubeswitch_state.json"), nil }
+	defer func() { stateFile = origStateFile }()
+
+	// Test switching context doesn't fail when state can't be saved.
+	if err := k.SetContext("default"); err != nil {
+		t.Errorf("Expected error to be %v, got %v", nil, err)
+	}
+}
+
+func TestSetNamespaceStateError(t *testing.T) {
+	k, _ := New()
+	loadNamespaces(k, 2)
+	k.config.Contexts["default"].Namespace = "Namespace1"
+	activeSession(t)
+
+	// Point state file inside a regular file so it can't be written.
+	file := filepath.Join(t.TempDir(), "file")
+	ioutil.WriteFile(file, nil, 0600)
+	origStateFile := stateFile
+	stateFile = func() (string, error) { return filepath.Join(file, "kubeswitch_state.json"), nil }
+	defer func() { stateFile = origStateFile }()
+
+	// Test namespace commands don't fail when state can't be saved.
+	if err := k.SetNamespace("Namespace2"); err != nil {
+		t.Errorf("Expected error to be %v, got %v", nil, err)
+	}
+	if err := k.SetContextNamespace("default", "Namespace1"); err != nil {
+		t.Errorf("Expected error to be %v, got %v", nil, err)
+	}
+	if err := k.UnsetNamespace(); err != nil {
+		t.Errorf("Expected error to be %v, got %v", nil, err)
+	}
+}
+
+func TestCleanupOnExit(t *testing.T) {
+	k, _ := New()
+	k.CleanupOnExit = true
+	os.Unsetenv(EnvVarActive)
+	defer os.Setenv(EnvVarConfig, os.Getenv(EnvVarConfig))
+	defer os.Unsetenv(EnvVarActive)
+
+	dir := t.TempDir()
+	origKubeDir := kubeDir
+	kubeDir = func() (string, error) { return dir, nil }
+	defer func() { kubeDir = origKubeDir }()
+
+	// Test session file is removed when shell exits.
+	k.Shell = "/bin/true"
+	if err := k.setupSession(); err != nil {
+		t.Errorf("Expected error to be %v, got %v", nil, err)
+	}
+	if _, err := os.Stat(os.Getenv(EnvVarConfig)); !os.IsNotExist(err) {
+		t.Errorf("Expected session file to be removed, got %v", err)
+	}
+
+	// Test session file is removed when shell exits with non-zero status.
+	os.Unsetenv(EnvVarActive)
+	k.Shell = "/bin/false"
+	var exitErr *ShellExitError
+	if err := k.setupSession(); !errors.As(err, &exitErr) {
+		t.Errorf("Expected shell exit error, got %v", err)
+	}
+	if _, err := os.Stat(os.Getenv(EnvVarConfig)); !os.IsNotExist(err) {
+		t.Errorf("Expected session file to be removed, got %v", err)
+	}
+}
+
+func TestPrintExport(t *testing.T) {
+	k, _ := New()
+	k.PrintExport = true
+	// Shell does not exist so setupSession fails if it is spawned.
+	k.Shell = "/nonexistent/bash"
+	os.Unsetenv(EnvVarActive)
+	defer os.Setenv(EnvVarConfig, os.Getenv(EnvVarConfig))
+	defer os.Unsetenv(EnvVarActive)
+
+	dir := t.TempDir()
+	origKubeDir := kubeDir
+	kubeDir = func() (string, error) { return dir, nil }
+	defer func() { kubeDir = origKubeDir }()
+
+	r, w, _ := os.Pipe()
+	origStdout := os.Stdout
+	os.Stdout = w
+	err := k.setupSession()
+	os.Stdout = origStdout
+	w.Close()
+	out, _ := ioutil.ReadAll(r)
+
+	if err != nil {
+		t.Errorf("Expected error to be %v, got %v", nil, err)
+	}
+	for _, line := range []string{
+		fmt.Sprintf("export %s='TRUE'", EnvVarActive),
+		fmt.Sprintf("export %s='%s'", EnvVarConfig, os.Getenv(EnvVarConfig)),
+	} {
+		if !strings.Contains(string(out), line) {
+			t.Errorf("Expected output to contain %q, got %q", line, out)
+		}
+	}
+	if _, err := os.Stat(os.Getenv(EnvVarConfig)); err != nil {
+		t.Errorf("Expected session file to exist, got %v", err)
+	}
+}
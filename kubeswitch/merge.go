@@ -0,0 +1,125 @@
+/*
+Copyright © 2020 Chung Tran <chung.k.tran@gmail.com>
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package kubeswitch
+
+import (
+	"path/filepath"
+	"strings"
+
+	api "k8s.io/client-go/tools/clientcmd/api"
+)
+
+// Merge returns an instance of Kubeswitch with config files from paths
+// flattened into a single config. When names collide, the entry from the
+// earlier file is kept. Set prefix to prefix context, cluster, and user names
+// with the name of their file so that entries of all files are kept.
+func Merge(paths []string, prefix bool) (*Kubeswitch, error) {
+	merged := api.NewConfig()
+
+	for _, path := range paths {
+		config, err := loadFile(path)
+		if err != nil {
+			return nil, err
+		}
+
+		// Flatten each file on its own since relative paths of
+		// certificates are resolved from the file's folder.
+		if err := api.FlattenConfig(config); err != nil {
+			return nil, err
+		}
+
+		if prefix {
+			base := filepath.Base(path)
+			prefixConfig(config, strings.TrimSuffix(base, filepath.Ext(base))+"-")
+		}
+
+		mergeConfig(merged, config, false)
+
+		// Use current context of the first file that has one.
+		if merged.CurrentContext == "" {
+			merged.CurrentContext = config.CurrentContext
+		}
+	}
+
+	return &Kubeswitch{config: merged}, nil
+}
+
+// MergeFile loads config file at path into loaded config so that contexts
+// missing from the loaded config files can be set. Entries of the file replace
+// loaded entries of the same names if overwrite is set.
+func (k *Kubeswitch) MergeFile(path string, overwrite bool) error {
+	config, err := loadFile(path)
+	if err != nil {
+		return err
+	}
+
+	// Flatten before merging since relative paths of certificates are
+	// resolved from the file's folder.
+	if err := api.FlattenConfig(config); err != nil {
+		return err
+	}
+
+	mergeConfig(k.config, config, overwrite)
+	return nil
+}
+
+// mergeConfig adds contexts, clusters, and users of src to dst. Entries of dst
+// are kept when names collide unless overwrite is set.
+func mergeConfig(dst, src *api.Config, overwrite bool) {
+	for name, cluster := range src.Clusters {
+		if _, ok := dst.Clusters[name]; overwrite || !ok {
+			dst.Clusters[name] = cluster
+		}
+	}
+	for name, user := range src.AuthInfos {
+		if _, ok := dst.AuthInfos[name]; overwrite || !ok {
+			dst.AuthInfos[name] = user
+		}
+	}
+	for name, ctx := range src.Contexts {
+		if _, ok := dst.Contexts[name]; overwrite || !ok {
+			dst.Contexts[name] = ctx
+		}
+	}
+}
+
+// prefixConfig prefixes names of contexts, clusters, and users in config
+// and updates references to them.
+func prefixConfig(config *api.Config, prefix string) {
+	clusters := map[string]*api.Cluster{}
+	for name, cluster := range config.Clusters {
+		clusters[prefix+name] = cluster
+	}
+	config.Clusters = clusters
+
+	users := map[string]*api.AuthInfo{}
+	for name, user := range config.AuthInfos {
+		users[prefix+name] = user
+	}
+	config.AuthInfos = users
+
+	ctxs := map[string]*api.Context{}
+	for name, ctx := range config.Contexts {
+		ctx.Cluster = prefix + ctx.Cluster
+		ctx.AuthInfo = prefix + ctx.AuthInfo
+		ctxs[prefix+name] = ctx
+	}
+	config.Contexts = ctxs
+
+	if config.CurrentContext != "" {
+		config.CurrentContext = prefix + config.CurrentContext
+	}
+}
@@ -0,0 +1,157 @@
+/*
+Copyright © 2020 Chung Tran <chung.k.tran@gmail.com>
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package kubeswitch
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+
+	"k8s.io/client-go/tools/clientcmd"
+)
+
+func TestMerge(t *testing.T) {
+	// Test colliding names keep entry of first file.
+	k, err := Merge([]string{"../fixtures/config.yaml", "../fixtures/config.json"}, false)
+	if err != nil {
+		t.Fatalf("Expected error to be %v, got %v", nil, err)
+	}
+	if ctxs := *k.ListContexts(); len(ctxs) != 1 {
+		t.Errorf("Expected length is %v, got %v", 1, len(ctxs))
+	}
+
+	// Test prefixed names keep entries of all files.
+	dir := t.TempDir()
+	data, _ := ioutil.ReadFile("../fixtures/config.yaml")
+	for _, name := range []string{"east.yaml", "west.yaml"} {
+		if err := ioutil.WriteFile(filepath.Join(dir, name), data, 0600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	k, err = Merge([]string{filepath.Join(dir, "east.yaml"), filepath.Join(dir, "west.yaml")}, true)
+	if err != nil {
+		t.Fatalf("Expected error to be %v, got %v", nil, err)
+	}
+	ctxs := *k.ListContexts()
+	sort.Strings(ctxs)
+	expected := []string{"east-default", "west-default"}
+	if !reflect.DeepEqual(ctxs, expected) {
+		t.Errorf("Expected contexts to be %v, got %v", expected, ctxs)
+	}
+	if c := k.config.Contexts["west-default"]; c.Cluster != "west-default" || c.AuthInfo != "west-default" {
+		t.Errorf("Expected context to reference %s, got %s and %s", "west-default", c.Cluster, c.AuthInfo)
+	}
+	if k.config.CurrentContext != "east-default" {
+		t.Errorf("Expected current context to be %s, got %s", "east-default", k.config.CurrentContext)
+	}
+
+	// Test merged config is written to file.
+	out := filepath.Join(dir, "merged.yaml")
+	if err := k.WriteToFile(out); err != nil {
+		t.Fatalf("Expected error to be %v, got %v", nil, err)
+	}
+	if config, err := clientcmd.LoadFromFile(out); err != nil || len(config.Clusters) != 2 {
+		t.Errorf("Expected merged file to have %d clusters, got %v, %v", 2, config, err)
+	}
+
+	// Test missing file returns error.
+	if _, err := Merge([]string{"../fixtures/missing.yaml"}, false); err == nil {
+		t.Errorf("Expected error for missing file, got %v", err)
+	}
+}
+
+// relativeCertConfig writes a config file referencing certificate file ca.crt
+// relative to its folder and returns the config path and certificate data.
+func relativeCertConfig(t *testing.T) (string, []byte) {
+	dir := filepath.Join(t.TempDir(), "sub")
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		t.Fatal(err)
+	}
+	ca := []byte("certificate")
+	if err := ioutil.WriteFile(filepath.Join(dir, "ca.crt"), ca, 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	config, _ := clientcmd.LoadFromFile("../fixtures/config.yaml")
+	for _, cluster := range config.Clusters {
+		cluster.CertificateAuthorityData = nil
+		cluster.CertificateAuthority = "ca.crt"
+	}
+	path := filepath.Join(dir, "cfg.yaml")
+	if err := clientcmd.WriteToFile(*config, path); err != nil {
+		t.Fatal(err)
+	}
+
+	return path, ca
+}
+
+func TestMergeRelativeCert(t *testing.T) {
+	path, ca := relativeCertConfig(t)
+
+	// Test relative certificate is resolved from the file's folder.
+	k, err := Merge([]string{path}, false)
+	if err != nil {
+		t.Fatalf("Expected error to be %v, got %v", nil, err)
+	}
+	if data := k.config.Clusters["default"].CertificateAuthorityData; string(data) != string(ca) {
+		t.Errorf("Expected certificate to be %s, got %s", ca, data)
+	}
+}
+
+func TestMergeFile(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "other.yaml")
+	data, _ := ioutil.ReadFile("../fixtures/config.yaml")
+	ioutil.WriteFile(path, []byte(strings.ReplaceAll(string(data), "name: default", "name: other")), 0600)
+
+	// Test contexts of file can be set after merging it.
+	k, _ := New()
+	if err := k.MergeFile(path, false); err != nil {
+		t.Fatalf("Expected error to be %v, got %v", nil, err)
+	}
+	if !k.IsValidContext("other") || !k.IsValidContext("default") {
+		t.Errorf("Expected contexts %v, got %v", []string{"default", "other"}, *k.ListContexts())
+	}
+
+	// Test loaded entries are only replaced with overwrite.
+	k.config.Clusters["other"].Server = "https://loaded"
+	k.MergeFile(path, false)
+	if server := k.config.Clusters["other"].Server; server != "https://loaded" {
+		t.Errorf("Expected server to be %v, got %v", "https://loaded", server)
+	}
+	k.MergeFile(path, true)
+	if server := k.config.Clusters["other"].Server; server == "https://loaded" {
+		t.Errorf("Expected server to be replaced, got %v", server)
+	}
+
+	// Test relative certificate is resolved from the file's folder.
+	certPath, ca := relativeCertConfig(t)
+	if err := k.MergeFile(certPath, true); err != nil {
+		t.Fatalf("Expected error to be %v, got %v", nil, err)
+	}
+	if data := k.config.Clusters["default"].CertificateAuthorityData; string(data) != string(ca) {
+		t.Errorf("Expected certificate to be %s, got %s", ca, data)
+	}
+
+	// Test missing file returns error.
+	if err := k.MergeFile(filepath.Join(dir, "missing.yaml"), false); err == nil {
+		t.Errorf("Expected error for missing file, got %v", err)
+	}
+}
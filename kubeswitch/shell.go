@@ -0,0 +1,163 @@
+/*
+Copyright © 2020 Chung Tran <chung.k.tran@gmail.com>
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package kubeswitch
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"os/exec"
+	"os/signal"
+	"os/user"
+	"path/filepath"
+	"strings"
+	"syscall"
+)
+
+const (
+	// defaultShell is the shell used when user's shell can't be determined.
+	defaultShell = "/bin/sh"
+)
+
+var (
+	// passwdFile is the file to look up user's login shell from.
+	passwdFile = "/etc/passwd"
+)
+
+// resolveShell returns the user's shell from SHELL env var. It falls back to
+// the user's login shell in passwdFile and then to defaultShell.
+func resolveShell() string {
+	if shell := os.Getenv("SHELL"); shell != "" {
+		return shell
+	}
+
+	if u, err := user.Current(); err == nil {
+		if shell := passwdShell(passwdFile, u.Username); shell != "" {
+			return shell
+		}
+	}
+
+	return defaultShell
+}
+
+// passwdShell returns the login shell of username in passwd file at path.
+// An empty string is returned if the user's shell can't be found.
+func passwdShell(path, username string) string {
+	f, err := os.Open(path)
+	if err != nil {
+		return ""
+	}
+	defer f.Close()
+
+	// Each line is formatted as name:password:uid:gid:gecos:home:shell.
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		fields := strings.Split(scanner.Text(), ":")
+		if len(fields) == 7 && fields[0] == username {
+			return fields[6]
+		}
+	}
+
+	return ""
+}
+
+// checkExecutable returns an error if file at path is not an executable file.
+func checkExecutable(path string) error {
+	info, err := os.Stat(path)
+	if err != nil {
+		return fmt.Errorf("invalid shell, %v", err)
+	}
+
+	if info.IsDir() || info.Mode()&0111 == 0 {
+		return fmt.Errorf("invalid shell, %s is not executable", path)
+	}
+
+	return nil
+}
+
+// ShellExitError is returned when a session's shell exits with a non-zero
+// status so that kubeswitch can exit with the same status.
+type ShellExitError struct {
+	// Code is the exit status of the shell, -1 if it was killed by a signal.
+	Code int
+}
+
+func (e *ShellExitError) Error() string {
+	return fmt.Sprintf("shell exited with status %d", e.Code)
+}
+
+// runShell runs shell attached to the terminal and waits for it to exit.
+// Signals sent to kubeswitch are forwarded to shell while it runs.
+func runShell(shell string) error {
+	err := runAttached(exec.Command(shell))
+	if exitErr, ok := err.(*exec.ExitError); ok {
+		return &ShellExitError{Code: exitErr.ExitCode()}
+	}
+
+	return err
+}
+
+// runAttached runs cmd attached to the terminal and waits for it to exit.
+// Streams already set on cmd are kept.
+// Signals sent to kubeswitch are forwarded to cmd while it runs.
+func runAttached(cmd *exec.Cmd) error {
+	if cmd.Stdin == nil {
+		cmd.Stdin = os.Stdin
+	}
+	if cmd.Stdout == nil {
+		cmd.Stdout = os.Stdout
+	}
+	if cmd.Stderr == nil {
+		cmd.Stderr = os.Stderr
+	}
+
+	// Catch signals before starting cmd so kubeswitch isn't terminated
+	// by signals meant for cmd, such as Ctrl-C from the terminal.
+	sigs := make(chan os.Signal, 1)
+	signal.Notify(sigs, os.Interrupt, syscall.SIGQUIT, syscall.SIGTERM, syscall.SIGHUP)
+	defer signal.Stop(sigs)
+
+	if err := cmd.Start(); err != nil {
+		return err
+	}
+
+	// Forward caught signals to cmd until it exits.
+	done := make(chan struct{})
+	defer close(done)
+	go func() {
+		for {
+			select {
+			case sig := <-sigs:
+				cmd.Process.Signal(sig)
+			case <-done:
+				return
+			}
+		}
+	}()
+
+	return cmd.Wait()
+}
+
+// exportCmd returns the command to export env var name with value for shell.
+// Value is single-quoted and escaped so it's never interpreted by the shell.
+func exportCmd(shell, name, value string) string {
+	if filepath.Base(shell) == "fish" {
+		value = strings.NewReplacer(`\`, `\\`, `'`, `\'`).Replace(value)
+		return fmt.Sprintf("set -gx %s '%s';", name, value)
+	}
+	value = strings.ReplaceAll(value, `'`, `'\''`)
+	return fmt.Sprintf("export %s='%s'", name, value)
+}
@@ -0,0 +1,124 @@
+/*
+Copyright © 2020 Chung Tran <chung.k.tran@gmail.com>
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package kubeswitch
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestResolveShell(t *testing.T) {
+	origShell := os.Getenv("SHELL")
+	defer os.Setenv("SHELL", origShell)
+
+	// Test SHELL env var is used when set.
+	os.Setenv("SHELL", "/bin/zsh")
+	if shell := resolveShell(); shell != "/bin/zsh" {
+		t.Errorf("Expected shell to be %v, got %v", "/bin/zsh", shell)
+	}
+
+	// Test default shell is used when SHELL is unset and passwd is missing.
+	os.Unsetenv("SHELL")
+	origPasswdFile := passwdFile
+	passwdFile = "/path/to/not/exists/passwd"
+	defer func() { passwdFile = origPasswdFile }()
+	if shell := resolveShell(); shell != defaultShell {
+		t.Errorf("Expected shell to be %v, got %v", defaultShell, shell)
+	}
+}
+
+func TestPasswdShell(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "passwd")
+	data := "root:x:0:0:root:/root:/bin/bash\nkube:x:1000:1000::/home/kube:/usr/bin/fish\n"
+	ioutil.WriteFile(path, []byte(data), 0600)
+
+	// Test login shell of existing user.
+	if shell := passwdShell(path, "kube"); shell != "/usr/bin/fish" {
+		t.Errorf("Expected shell to be %v, got %v", "/usr/bin/fish", shell)
+	}
+
+	// Test unknown user.
+	if shell := passwdShell(path, "unknown"); shell != "" {
+		t.Errorf("Expected shell to be %v, got %v", "", shell)
+	}
+}
+
+func TestCheckExecutable(t *testing.T) {
+	dir := t.TempDir()
+	exe := filepath.Join(dir, "shell")
+	ioutil.WriteFile(exe, []byte("#!/bin/sh\n"), 0755)
+	noExe := filepath.Join(dir, "config")
+	ioutil.WriteFile(noExe, []byte(""), 0600)
+
+	// Test executable file.
+	if err := checkExecutable(exe); err != nil {
+		t.Errorf("Expected error to be %v, got %v", nil, err)
+	}
+
+	// Test non-executable file, directory, and missing file.
+	for _, path := range []string{noExe, dir, filepath.Join(dir, "missing")} {
+		if err := checkExecutable(path); err == nil {
+			t.Errorf("Expected error for %s, got %v", path, err)
+		}
+	}
+}
+
+func TestRunShell(t *testing.T) {
+	// Test shell exiting with zero status is not an error.
+	if err := runShell("/bin/true"); err != nil {
+		t.Errorf("Expected error to be %v, got %v", nil, err)
+	}
+
+	// Test exit status of shell is returned.
+	var exitErr *ShellExitError
+	if err := runShell("/bin/false"); !errors.As(err, &exitErr) || exitErr.Code != 1 {
+		t.Errorf("Expected exit status to be %d, got %v", 1, err)
+	}
+
+	// Test shell that can't be started.
+	if err := runShell("/path/to/not/exists/shell"); err == nil {
+		t.Errorf("Expected error for missing shell, got %v", err)
+	}
+}
+
+func TestExportCmd(t *testing.T) {
+	// Test export for POSIX shells.
+	expected := "export KUBECONFIG='/tmp/config'"
+	if cmd := exportCmd("/bin/bash", EnvVarConfig, "/tmp/config"); cmd != expected {
+		t.Errorf("Expected command to be %v, got %v", expected, cmd)
+	}
+
+	// Test export for fish shell.
+	expected = "set -gx KUBECONFIG '/tmp/config';"
+	if cmd := exportCmd("/usr/bin/fish", EnvVarConfig, "/tmp/config"); cmd != expected {
+		t.Errorf("Expected command to be %v, got %v", expected, cmd)
+	}
+
+	// Test quotes in values are escaped for POSIX shells.
+	expected = `export KUBESWITCH_CONTEXT='it'\''s'`
+	if cmd := exportCmd("/bin/bash", EnvVarContext, "it's"); cmd != expected {
+		t.Errorf("Expected command to be %v, got %v", expected, cmd)
+	}
+
+	// Test quotes and backslashes in values are escaped for fish shell.
+	expected = `set -gx KUBESWITCH_CONTEXT 'it\'s\\';`
+	if cmd := exportCmd("/usr/bin/fish", EnvVarContext, `it's\`); cmd != expected {
+		t.Errorf("Expected command to be %v, got %v", expected, cmd)
+	}
+}
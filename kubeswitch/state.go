@@ -0,0 +1,127 @@
+/*
+Copyright © 2020 Chung Tran <chung.k.tran@gmail.com>
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package kubeswitch
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"time"
+)
+
+var (
+	// stateFile stores kubeswitch state that persists across sessions.
+	// It lives outside of sessionDir so it is not purged.
+	stateFile = func() (string, error) {
+		dir, err := kubeDir()
+		if err != nil {
+			return "", err
+		}
+		return dir + "/kubeswitch_state.json", nil
+	}
+)
+
+// state holds data that persists across kubeswitch sessions.
+type state struct {
+	// Namespaces maps context names to their last selected namespace.
+	Namespaces map[string]string `json:"namespaces,omitempty"`
+
+	// LastUsed maps context names to times their namespaces were last selected.
+	LastUsed map[string]map[string]time.Time `json:"lastUsed,omitempty"`
+
+	// ContextsUsed maps context names to times they were last switched to.
+	ContextsUsed map[string]time.Time `json:"contextsUsed,omitempty"`
+}
+
+// loadState reads state from stateFile. An empty state is returned
+// if stateFile does not exist yet.
+func loadState() (*state, error) {
+	s := &state{
+		Namespaces:   map[string]string{},
+		LastUsed:     map[string]map[string]time.Time{},
+		ContextsUsed: map[string]time.Time{},
+	}
+
+	path, err := stateFile()
+	if err != nil {
+		return nil, err
+	}
+
+	data, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		return s, nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	if err := json.Unmarshal(data, s); err != nil {
+		return nil, err
+	}
+
+	// Ensure maps are usable when missing from the file.
+	if s.Namespaces == nil {
+		s.Namespaces = map[string]string{}
+	}
+	if s.LastUsed == nil {
+		s.LastUsed = map[string]map[string]time.Time{}
+	}
+	if s.ContextsUsed == nil {
+		s.ContextsUsed = map[string]time.Time{}
+	}
+
+	return s, nil
+}
+
+// setNamespace remembers ns as the last selected namespace of context ctx
+// and records when it was selected.
+func (s *state) setNamespace(ctx, ns string) {
+	s.Namespaces[ctx] = ns
+	if s.LastUsed[ctx] == nil {
+		s.LastUsed[ctx] = map[string]time.Time{}
+	}
+	s.LastUsed[ctx][ns] = time.Now()
+}
+
+// setContext records when context ctx was switched to.
+func (s *state) setContext(ctx string) {
+	s.ContextsUsed[ctx] = time.Now()
+}
+
+// updateState loads state, applies fn to it and saves it.
+func updateState(fn func(*state)) error {
+	s, err := loadState()
+	if err != nil {
+		return err
+	}
+	fn(s)
+
+	return s.save()
+}
+
+// save writes state to stateFile atomically.
+func (s *state) save() error {
+	data, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	path, err := stateFile()
+	if err != nil {
+		return err
+	}
+
+	return writeFile(path, data)
+}
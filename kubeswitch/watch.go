@@ -0,0 +1,107 @@
+/*
+Copyright © 2020 Chung Tran <chung.k.tran@gmail.com>
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package kubeswitch
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/watch"
+)
+
+// WatchNamespaces loads namespaces of current context and keeps them current
+// by watching for changes. onChange is called with namespace names once loaded
+// and after each change. It returns when no change is seen for idle, or never
+// times out if idle is zero.
+func (k *Kubeswitch) WatchNamespaces(idle time.Duration, onChange func([]string)) error {
+	return k.WatchNamespacesContext(context.Background(), idle, onChange)
+}
+
+// WatchNamespacesContext is like WatchNamespaces but stops watching when ctx is done.
+func (k *Kubeswitch) WatchNamespacesContext(ctx context.Context, idle time.Duration, onChange func([]string)) error {
+	if err := k.LoadNamespacesContext(ctx); err != nil {
+		return err
+	}
+
+	kube, err := k.client()
+	if err != nil {
+		return err
+	}
+
+	// Watch from the listed version so no change is missed.
+	w, err := kube.CoreV1().Namespaces().Watch(ctx, metav1.ListOptions{ResourceVersion: k.namespaces.ResourceVersion})
+	if err != nil {
+		return err
+	}
+	defer w.Stop()
+	onChange(*k.ListNamespaces())
+
+	// A nil channel never fires so there's no idle cap without idle.
+	var timeout <-chan time.Time
+	var timer *time.Timer
+	if idle > 0 {
+		timer = time.NewTimer(idle)
+		defer timer.Stop()
+		timeout = timer.C
+	}
+
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-timeout:
+			Logf("No namespace changes for %s, stopping watch", idle)
+			return nil
+		case ev, ok := <-w.ResultChan():
+			if !ok {
+				return fmt.Errorf("watch of namespaces in context %s closed", k.config.CurrentContext)
+			}
+			if ev.Type == watch.Error {
+				return apierrors.FromObject(ev.Object)
+			}
+
+			ns, ok := ev.Object.(*corev1.Namespace)
+			if !ok {
+				continue
+			}
+			Logf("Namespace %s %s", ns.Name, ev.Type)
+			k.updateNamespace(ev.Type, ns)
+			onChange(*k.ListNamespaces())
+
+			if timer != nil {
+				timer.Reset(idle)
+			}
+		}
+	}
+}
+
+// updateNamespace applies a watch event of type t for ns to loaded namespaces.
+func (k *Kubeswitch) updateNamespace(t watch.EventType, ns *corev1.Namespace) {
+	items := k.namespaces.Items[:0]
+	for _, n := range k.namespaces.Items {
+		if n.Name != ns.Name {
+			items = append(items, n)
+		}
+	}
+	if t != watch.Deleted {
+		items = append(items, *ns)
+	}
+	k.namespaces.Items = items
+}
@@ -0,0 +1,68 @@
+/*
+Copyright © 2020 Chung Tran <chung.k.tran@gmail.com>
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package kubeswitch
+
+import (
+	"context"
+	"reflect"
+	"testing"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/kubernetes/fake"
+	"k8s.io/client-go/rest"
+)
+
+func TestWatchNamespaces(t *testing.T) {
+	ns := &corev1.Namespace{}
+	ns.Name = "Namespace1"
+	client := fake.NewSimpleClientset(ns)
+
+	k, _ := New()
+	k.clientFactory = func(*rest.Config) (kubernetes.Interface, error) {
+		return client, nil
+	}
+
+	// Create and delete namespaces once the watch is running.
+	changes := make(chan []string, 10)
+	go func() {
+		<-changes
+		created := &corev1.Namespace{}
+		created.Name = "Namespace2"
+		client.CoreV1().Namespaces().Create(context.Background(), created, metav1.CreateOptions{})
+		<-changes
+		client.CoreV1().Namespaces().Delete(context.Background(), "Namespace1", metav1.DeleteOptions{})
+	}()
+
+	var seen [][]string
+	err := k.WatchNamespaces(100*time.Millisecond, func(nss []string) {
+		seen = append(seen, nss)
+		changes <- nss
+	})
+
+	// Test watch stops without error when idle.
+	if err != nil {
+		t.Errorf("Expected error to be %v, got %v", nil, err)
+	}
+
+	// Test namespaces are kept current.
+	expected := [][]string{{"Namespace1"}, {"Namespace1", "Namespace2"}, {"Namespace2"}}
+	if !reflect.DeepEqual(seen, expected) {
+		t.Errorf("Expected namespaces to be %v, got %v", expected, seen)
+	}
+}
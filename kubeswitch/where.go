@@ -0,0 +1,49 @@
+/*
+Copyright © 2020 Chung Tran <chung.k.tran@gmail.com>
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package kubeswitch
+
+import (
+	"os"
+	"path/filepath"
+
+	"k8s.io/client-go/tools/clientcmd"
+)
+
+// Where returns absolute paths of config files in KUBECONFIG, or the default
+// config file, that define context name. Each file is loaded separately so
+// that all files defining name are found.
+func Where(name string) ([]string, error) {
+	var paths []string
+
+	for _, path := range clientcmd.NewDefaultPathOptions().GetLoadingPrecedence() {
+		config, err := loadFile(path)
+		if os.IsNotExist(err) {
+			continue
+		} else if err != nil {
+			return nil, err
+		}
+
+		if _, ok := config.Contexts[name]; ok {
+			abs, err := filepath.Abs(path)
+			if err != nil {
+				return nil, err
+			}
+			paths = append(paths, abs)
+		}
+	}
+
+	return paths, nil
+}
@@ -0,0 +1,47 @@
+/*
+Copyright © 2020 Chung Tran <chung.k.tran@gmail.com>
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package kubeswitch
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestWhere(t *testing.T) {
+	defer os.Setenv("KUBECONFIG", os.Getenv("KUBECONFIG"))
+	os.Setenv("KUBECONFIG", "../fixtures/config.yaml"+string(filepath.ListSeparator)+"../fixtures/missing.yaml"+string(filepath.ListSeparator)+"../fixtures/config.json")
+
+	// Test all files defining context are returned.
+	yamlPath, _ := filepath.Abs("../fixtures/config.yaml")
+	jsonPath, _ := filepath.Abs("../fixtures/config.json")
+	expected := []string{yamlPath, jsonPath}
+	if paths, err := Where("default"); err != nil || !reflect.DeepEqual(paths, expected) {
+		t.Errorf("Expected paths to be %v, got %v, %v", expected, paths, err)
+	}
+
+	// Test unknown context has no paths.
+	if paths, err := Where("unknown"); err != nil || len(paths) != 0 {
+		t.Errorf("Expected no paths, got %v, %v", paths, err)
+	}
+
+	// Test malformed file returns error.
+	os.Setenv("KUBECONFIG", "../fixtures/malformed.yaml")
+	if _, err := Where("default"); err == nil {
+		t.Errorf("Expected error for malformed file, got %v", err)
+	}
+}